@@ -682,7 +682,7 @@ func (a *Agent) push(
 			aggregator.Push(acc)
 			break
 		case <-ctx.Done():
-			aggregator.Push(acc)
+			aggregator.Flush(acc)
 			return
 		}
 	}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"sort"
@@ -927,7 +928,44 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	// The aggregators of sliding and event time windows get the settings of
+	// a template parsed once, the aggregator itself is initialized and may
+	// have changed them.
+	template := creator()
+	if err := toml.UnmarshalTable(table, template); err != nil {
+		return err
+	}
+
+	ra := models.NewRunningAggregator(aggregator, conf)
+	ra.Creator = func() (telegraf.Aggregator, error) {
+		aggregator := creator()
+		if err := copySettings(aggregator, template); err != nil {
+			return nil, err
+		}
+		return aggregator, nil
+	}
+	c.Aggregators = append(c.Aggregators, ra)
+	return nil
+}
+
+// copySettings copies the exported fields, the settings, of the plugin src to
+// dst of the same type.  The unexported fields of dst, such as caches made by
+// the constructor, are kept so they are not shared.
+func copySettings(dst, src interface{}) error {
+	d, s := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if d.Type() != s.Type() || d.Kind() != reflect.Ptr || d.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot copy the settings of %T", src)
+	}
+	d, s = d.Elem(), s.Elem()
+
+	t := d.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("toml") == "-" {
+			continue
+		}
+		d.Field(i).Set(s.Field(i))
+	}
 	return nil
 }
 
@@ -966,20 +1004,141 @@ func (c *Config) newRunningProcessor(
 ) (*models.RunningProcessor, error) {
 	processor := creator()
 
+	var plugin interface{} = processor
 	if p, ok := processor.(unwrappable); ok {
-		if err := toml.UnmarshalTable(table, p.Unwrap()); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := toml.UnmarshalTable(table, processor); err != nil {
+		plugin = p.Unwrap()
+	}
+
+	if b, ok := plugin.(models.BranchingProcessor); ok {
+		var err error
+		table, err = c.buildBranches(b, name, table)
+		if err != nil {
 			return nil, err
 		}
 	}
 
+	if err := toml.UnmarshalTable(table, plugin); err != nil {
+		return nil, err
+	}
+
 	rf := models.NewRunningProcessor(processor, processorConfig)
 	return rf, nil
 }
 
+// buildBranches creates the processor chains of a branching processor from
+// its "case" and "default" tables and returns the remaining plugin table.
+// The tables are copied before parsing as the processor is created twice, once
+// for the aggregators.
+func (c *Config) buildBranches(
+	b models.BranchingProcessor,
+	name string,
+	table *ast.Table,
+) (*ast.Table, error) {
+	table = copyTable(table)
+
+	var cases []*models.ProcessorBranch
+	if node, ok := table.Fields["case"]; ok {
+		tbls, ok := node.([]*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: case must be an array of tables", name)
+		}
+		for _, tbl := range tbls {
+			tbl = copyTable(tbl)
+			filter, err := buildFilter(tbl)
+			if err != nil {
+				return nil, err
+			}
+			if !filter.IsActive() {
+				return nil, fmt.Errorf("%s: case without filter", name)
+			}
+			chain, err := c.buildProcessorChain(name, tbl)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, &models.ProcessorBranch{Filter: filter, Processors: chain})
+		}
+	}
+
+	var fallback models.RunningProcessors
+	if node, ok := table.Fields["default"]; ok {
+		tbl, ok := node.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: default must be a table", name)
+		}
+		var err error
+		fallback, err = c.buildProcessorChain(name, copyTable(tbl))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	delete(table.Fields, "case")
+	delete(table.Fields, "default")
+
+	b.SetBranches(cases, fallback)
+	return table, nil
+}
+
+// buildProcessorChain creates the processors defined in the "processors"
+// table of a branch, sorted by their order.
+func (c *Config) buildProcessorChain(name string, tbl *ast.Table) (models.RunningProcessors, error) {
+	var chain models.RunningProcessors
+	if node, ok := tbl.Fields["processors"]; ok {
+		subTable, ok := node.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: processors must be a table", name)
+		}
+		// Processors with the same order run sorted by name.
+		names := make([]string, 0, len(subTable.Fields))
+		for pluginName := range subTable.Fields {
+			names = append(names, pluginName)
+		}
+		sort.Strings(names)
+
+		for _, pluginName := range names {
+			pluginTables, ok := subTable.Fields[pluginName].([]*ast.Table)
+			if !ok {
+				return nil, fmt.Errorf("%s: processors.%s must be an array of tables", name, pluginName)
+			}
+			creator, ok := processors.Processors[pluginName]
+			if !ok {
+				return nil, fmt.Errorf("Undefined but requested processor: %s", pluginName)
+			}
+			for _, t := range pluginTables {
+				t = copyTable(t)
+				processorConfig, err := buildProcessor(pluginName, t)
+				if err != nil {
+					return nil, err
+				}
+				rp, err := c.newRunningProcessor(creator, processorConfig, pluginName, t)
+				if err != nil {
+					return nil, err
+				}
+				chain = append(chain, rp)
+			}
+		}
+		delete(tbl.Fields, "processors")
+	}
+
+	for key := range tbl.Fields {
+		return nil, fmt.Errorf("%s: unknown branch option %q", name, key)
+	}
+
+	sort.Stable(chain)
+	return chain, nil
+}
+
+// copyTable returns a shallow copy of the table, fields can be removed from
+// the copy without changing the original.
+func copyTable(tbl *ast.Table) *ast.Table {
+	t := *tbl
+	t.Fields = make(map[string]interface{}, len(tbl.Fields))
+	for k, v := range tbl.Fields {
+		t.Fields[k] = v
+	}
+	return &t
+}
+
 func (c *Config) addOutput(name string, table *ast.Table) error {
 	if len(c.OutputFilters) > 0 && !sliceContains(name, c.OutputFilters) {
 		return nil
@@ -1091,6 +1250,26 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		return nil, err
 	}
 
+	if err := getConfigDuration(tbl, "step", &conf.Step); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigDuration(tbl, "allowed_lateness", &conf.AllowedLateness); err != nil {
+		return nil, err
+	}
+
+	if node, ok := tbl.Fields["event_time"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				conf.EventTime, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing boolean value for %s: %s", name, err)
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["drop_original"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -1144,12 +1323,39 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["group_by"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						conf.GroupBy = append(conf.GroupBy, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["drop_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						conf.DropTags = append(conf.DropTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "drop_original")
+	delete(tbl.Fields, "event_time")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "tags")
+	delete(tbl.Fields, "group_by")
+	delete(tbl.Fields, "drop_tags")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1822,6 +2028,86 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["avro_schema_registry"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchemaRegistry = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_schema"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchema = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_measurement"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroMeasurement = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroTags = append(c.AvroTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroFields = append(c.AvroFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestamp = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimezone = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_field_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroFieldSeparator = str.Value
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1866,6 +2152,15 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timezone")
 	delete(tbl.Fields, "csv_trim_space")
 	delete(tbl.Fields, "form_urlencoded_tag_keys")
+	delete(tbl.Fields, "avro_schema_registry")
+	delete(tbl.Fields, "avro_schema")
+	delete(tbl.Fields, "avro_measurement")
+	delete(tbl.Fields, "avro_tags")
+	delete(tbl.Fields, "avro_fields")
+	delete(tbl.Fields, "avro_timestamp")
+	delete(tbl.Fields, "avro_timestamp_format")
+	delete(tbl.Fields, "avro_timezone")
+	delete(tbl.Fields, "avro_field_separator")
 
 	return c, nil
 }
@@ -1916,6 +2211,14 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["batch_template"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.BatchTemplate = str.Value
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["influx_max_line_bytes"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if integer, ok := kv.Value.(*ast.Integer); ok {
@@ -2072,6 +2375,54 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["csv_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumns = append(c.CSVColumns, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_delimiter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVDelimiter = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_header"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.CSVHeader, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_layout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVLayout = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampFormat = str.Value
+			}
+		}
+	}
+
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
@@ -2081,6 +2432,7 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
 	delete(tbl.Fields, "templates")
+	delete(tbl.Fields, "batch_template")
 	delete(tbl.Fields, "json_timestamp_units")
 	delete(tbl.Fields, "splunkmetric_hec_routing")
 	delete(tbl.Fields, "splunkmetric_multimetric")
@@ -2089,6 +2441,11 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "prometheus_export_timestamp")
 	delete(tbl.Fields, "prometheus_sort_metrics")
 	delete(tbl.Fields, "prometheus_string_as_label")
+	delete(tbl.Fields, "csv_columns")
+	delete(tbl.Fields, "csv_delimiter")
+	delete(tbl.Fields, "csv_header")
+	delete(tbl.Fields, "csv_layout")
+	delete(tbl.Fields, "csv_timestamp_format")
 	return serializers.NewSerializer(c)
 }
 
@@ -7,6 +7,8 @@ import (
 
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/aggregators/basicstats"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/minmax"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/http_listener_v2"
@@ -14,6 +16,10 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors/branch"
+	_ "github.com/influxdata/telegraf/plugins/processors/override"
+	_ "github.com/influxdata/telegraf/plugins/processors/rename"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -257,3 +263,89 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error loading config file ./testdata/non_slice_slice.toml: Error parsing http array, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_ProcessorBranches(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/processor_branch.toml")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Processors))
+	require.Equal(t, 1, len(c.AggProcessors))
+	require.Equal(t, int64(1), c.Processors[0].Config.Order)
+
+	b, ok := c.Processors[0].Processor.(*branch.Branch)
+	require.True(t, ok)
+	cases, fallback := b.Branches()
+	require.Len(t, cases, 2)
+	require.Equal(t, []string{"cpu"}, cases[0].Filter.NamePass)
+	require.Len(t, cases[0].Processors, 2)
+	require.Equal(t, "rename", cases[0].Processors[0].Config.Name)
+	require.Equal(t, "override", cases[0].Processors[1].Config.Name)
+	require.Len(t, cases[1].Filter.TagPass, 1)
+	require.Equal(t, "env", cases[1].Filter.TagPass[0].Name)
+	require.Equal(t, []string{"dev"}, cases[1].Filter.TagPass[0].Filter)
+	require.Len(t, cases[1].Processors, 0)
+	require.Len(t, fallback, 1)
+
+	// The aggregator copy must not share processors or lose the filters.
+	aggBranch := c.AggProcessors[0].Processor.(*branch.Branch)
+	aggCases, aggFallback := aggBranch.Branches()
+	require.Len(t, aggCases, 2)
+	require.Equal(t, []string{"cpu"}, aggCases[0].Filter.NamePass)
+	require.NotSame(t, cases[0].Processors[0], aggCases[0].Processors[0])
+	require.Len(t, aggFallback, 1)
+}
+
+func TestConfig_ProcessorBranchUnknownOption(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[processors.branch]]
+  [[processors.branch.case]]
+    namepass = ["cpu"]
+    name_override = "cpu2"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown branch option "name_override"`)
+}
+
+func TestConfig_AggregatorGroupBy(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[aggregators.minmax]]
+  period = "30s"
+  group_by = ["role", "dc"]
+  drop_tags = ["dc"]
+`))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Aggregators))
+	require.Equal(t, []string{"role", "dc"}, c.Aggregators[0].Config.GroupBy)
+	require.Equal(t, []string{"dc"}, c.Aggregators[0].Config.DropTags)
+}
+
+func TestConfig_AggregatorWindows(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[aggregators.basicstats]]
+  period = "30s"
+  step = "10s"
+  stats = ["count"]
+`))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Aggregators))
+
+	// Each window gets the settings but not the state of another window.
+	a, err := c.Aggregators[0].Creator()
+	require.NoError(t, err)
+	b, err := c.Aggregators[0].Creator()
+	require.NoError(t, err)
+	require.Equal(t, []string{"count"}, a.(*basicstats.BasicStats).Stats)
+	require.Equal(t, []string{"count"}, b.(*basicstats.BasicStats).Stats)
+	require.NoError(t, a.(*basicstats.BasicStats).Init())
+	require.NoError(t, b.(*basicstats.BasicStats).Init())
+
+	a.Add(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)))
+	var acc testutil.Accumulator
+	b.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+	a.Push(&acc)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
@@ -71,6 +71,14 @@ func getFilenameRotationTemplate(filename string) string {
 	return stem + ".%s-%s" + fileExt
 }
 
+// Size returns the size of the current file, which is 0 for a new file
+// after rotating.
+func (w *FileWriter) Size() int64 {
+	w.Lock()
+	defer w.Unlock()
+	return w.bytesWritten
+}
+
 // Write writes p to the current file, then checks to see if
 // rotation is necessary.
 func (w *FileWriter) Write(p []byte) (n int, err error) {
@@ -146,3 +146,22 @@ func TestFileWriter_CloseRotates(t *testing.T) {
 	assert.Equal(t, 1, len(files))
 	assert.Regexp(t, "^test\\.[^\\.]+\\.log$", files[0].Name())
 }
+
+func TestFileWriter_Size(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationSize")
+	require.NoError(t, err)
+	maxSize := int64(9)
+	writer, err := NewFileWriter(filepath.Join(tempDir, "test.log"), 0, maxSize, -1)
+	require.NoError(t, err)
+	defer func() { writer.Close(); os.RemoveAll(tempDir) }()
+	w := writer.(*FileWriter)
+
+	_, err = w.Write([]byte("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), w.Size())
+
+	// The file is rotated after this write.
+	_, err = w.Write([]byte(" World"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), w.Size())
+}
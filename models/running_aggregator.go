@@ -1,10 +1,14 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
@@ -17,6 +21,20 @@ type RunningAggregator struct {
 	periodEnd   time.Time
 	log         telegraf.Logger
 
+	groupBy  filter.Filter
+	dropTags filter.Filter
+
+	// Creator returns a new configured instance of the aggregator, it is
+	// required for sliding and event time windows.
+	Creator func() (telegraf.Aggregator, error)
+
+	// windows are the aggregators of the open sliding or event time windows
+	// by the unix time in nanoseconds of their start.  Windows ending at or
+	// before closed do not accept metrics anymore and are pushed.
+	windows  map[int64]telegraf.Aggregator
+	closed   time.Time
+	pushTime time.Time
+
 	MetricsPushed   selfstat.Stat
 	MetricsFiltered selfstat.Stat
 	MetricsDropped  selfstat.Stat
@@ -78,6 +96,19 @@ type AggregatorConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// GroupBy and DropTags reduce the tags of the metrics before they are
+	// added, so the aggregation is done per remaining tag set.
+	GroupBy  []string
+	DropTags []string
+
+	// Step is the time between the start of two sliding windows.  With
+	// EventTime the windows are closed by the metric timestamps instead of
+	// the clock.  AllowedLateness is the time windows are kept open after
+	// their end.
+	Step            time.Duration
+	EventTime       bool
+	AllowedLateness time.Duration
 }
 
 func (r *RunningAggregator) LogName() string {
@@ -85,6 +116,29 @@ func (r *RunningAggregator) LogName() string {
 }
 
 func (r *RunningAggregator) Init() error {
+	var err error
+	r.groupBy, err = filter.Compile(r.Config.GroupBy)
+	if err != nil {
+		return fmt.Errorf("error compiling group_by: %w", err)
+	}
+	r.dropTags, err = filter.Compile(r.Config.DropTags)
+	if err != nil {
+		return fmt.Errorf("error compiling drop_tags: %w", err)
+	}
+
+	if r.windowed() {
+		if r.Config.Step == 0 {
+			r.Config.Step = r.Config.Period
+		}
+		if r.Config.Step <= 0 || r.Config.Step > r.Config.Period || r.Config.Period%r.Config.Step != 0 {
+			return errors.New("step must divide the period")
+		}
+		if r.Creator == nil {
+			return errors.New("aggregator does not support windows")
+		}
+		r.windows = make(map[int64]telegraf.Aggregator)
+	}
+
 	if p, ok := r.Aggregator.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -94,7 +148,18 @@ func (r *RunningAggregator) Init() error {
 	return nil
 }
 
+// windowed returns true if the aggregator uses sliding or event time windows
+// instead of a single window based on the clock.
+func (r *RunningAggregator) windowed() bool {
+	return r.Config.Step > 0 || r.Config.EventTime
+}
+
+// Period returns the time between two pushes, the step of the windows if
+// windowed.
 func (r *RunningAggregator) Period() time.Duration {
+	if r.windowed() {
+		return r.Config.Step
+	}
 	return r.Config.Period
 }
 
@@ -119,6 +184,9 @@ func (r *RunningAggregator) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 
 	if m != nil {
 		m.SetAggregate(true)
+		if !r.pushTime.IsZero() {
+			m.SetTime(r.pushTime)
+		}
 	}
 
 	r.MetricsPushed.Incr(1)
@@ -144,10 +212,16 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 		r.MetricsFiltered.Incr(1)
 		return r.Config.DropOriginal
 	}
+	r.group(m)
 
 	r.Lock()
 	defer r.Unlock()
 
+	if r.windowed() {
+		r.addWindowed(m)
+		return r.Config.DropOriginal
+	}
+
 	if m.Time().Before(r.periodStart.Add(-r.Config.Grace)) || m.Time().After(r.periodEnd.Add(r.Config.Delay)) {
 		r.log.Debugf("Metric is outside aggregation window; discarding. %s: m: %s e: %s g: %s",
 			m.Time(), r.periodStart, r.periodEnd, r.Config.Grace)
@@ -159,25 +233,155 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 	return r.Config.DropOriginal
 }
 
+// addWindowed adds the metric to all open windows containing its timestamp.
+func (r *RunningAggregator) addWindowed(m telegraf.Metric) {
+	ts := m.Time()
+
+	var added bool
+	for start := r.windowStart(ts); start.Add(r.Config.Period).After(ts); start = start.Add(-r.Config.Step) {
+		if !start.Add(r.Config.Period).After(r.closed) {
+			break
+		}
+
+		agg, ok := r.windows[start.UnixNano()]
+		if !ok {
+			var err error
+			agg, err = r.newWindow()
+			if err != nil {
+				r.log.Errorf("Could not create window: %v", err)
+				return
+			}
+			r.windows[start.UnixNano()] = agg
+		}
+		agg.Add(m)
+		added = true
+	}
+
+	if !added {
+		r.log.Debugf("Metric is outside open windows; discarding. %s: closed: %s", ts, r.closed)
+		r.MetricsDropped.Incr(1)
+	}
+
+	if r.Config.EventTime {
+		if watermark := ts.Add(-r.Config.AllowedLateness); watermark.After(r.closed) {
+			r.closed = watermark
+		}
+	}
+}
+
+// windowStart returns the start of the last window containing ts.  Windows
+// start every step from the end of the period set by UpdateWindow, so they
+// are aligned like the periods of aggregators without windows, depending on
+// round_interval.
+func (r *RunningAggregator) windowStart(ts time.Time) time.Time {
+	if r.periodEnd.IsZero() {
+		return ts.Truncate(r.Config.Step)
+	}
+	offset := ts.Sub(r.periodEnd) % r.Config.Step
+	if offset < 0 {
+		offset += r.Config.Step
+	}
+	return ts.Add(-offset)
+}
+
+func (r *RunningAggregator) newWindow() (telegraf.Aggregator, error) {
+	agg, err := r.Creator()
+	if err != nil {
+		return nil, err
+	}
+	setLoggerOnPlugin(agg, r.log)
+	if p, ok := agg.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return nil, err
+		}
+	}
+	return agg, nil
+}
+
+// group removes the tags not in group_by and the tags in drop_tags.
+func (r *RunningAggregator) group(m telegraf.Metric) {
+	if r.groupBy == nil && r.dropTags == nil {
+		return
+	}
+
+	var remove []string
+	for _, tag := range m.TagList() {
+		if r.groupBy != nil && !r.groupBy.Match(tag.Key) {
+			remove = append(remove, tag.Key)
+		} else if r.dropTags != nil && r.dropTags.Match(tag.Key) {
+			remove = append(remove, tag.Key)
+		}
+	}
+	for _, key := range remove {
+		m.RemoveTag(key)
+	}
+}
+
 func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 	r.Lock()
 	defer r.Unlock()
 
 	since := r.periodEnd
-	until := r.periodEnd.Add(r.Config.Period)
+	until := r.periodEnd.Add(r.Period())
 	r.UpdateWindow(since, until)
 
+	if r.windowed() {
+		if !r.Config.EventTime {
+			r.closed = since.Add(-r.Config.AllowedLateness)
+		}
+		r.pushWindows(acc, false)
+		return
+	}
+
 	r.push(acc)
 	r.Aggregator.Reset()
 }
 
+// Flush pushes the aggregates of all windows, including open ones, and is
+// called on shutdown.
+func (r *RunningAggregator) Flush(acc telegraf.Accumulator) {
+	if !r.windowed() {
+		r.Push(acc)
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.pushWindows(acc, true)
+}
+
 func (r *RunningAggregator) push(acc telegraf.Accumulator) {
+	r.pushAggregator(r.Aggregator, acc)
+}
+
+func (r *RunningAggregator) pushAggregator(agg telegraf.Aggregator, acc telegraf.Accumulator) {
 	start := time.Now()
-	r.Aggregator.Push(acc)
+	agg.Push(acc)
 	elapsed := time.Since(start)
 	r.PushTime.Incr(elapsed.Nanoseconds())
 }
 
+// pushWindows pushes the closed windows, or all windows, in order.  The
+// aggregates have the end of their window as timestamp.
+func (r *RunningAggregator) pushWindows(acc telegraf.Accumulator, all bool) {
+	starts := make([]int64, 0, len(r.windows))
+	for start := range r.windows {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	for _, start := range starts {
+		end := time.Unix(0, start).Add(r.Config.Period)
+		if !all && end.After(r.closed) {
+			break
+		}
+		r.pushTime = end
+		r.pushAggregator(r.windows[start], acc)
+		delete(r.windows, start)
+	}
+	r.pushTime = time.Time{}
+}
+
 func (r *RunningAggregator) Log() telegraf.Logger {
 	return r.log
 }
@@ -238,6 +238,230 @@ func TestAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func TestAddGroupBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupBy  []string
+		dropTags []string
+		expected map[string]string
+	}{
+		{
+			name:     "group by",
+			groupBy:  []string{"role", "dc*"},
+			expected: map[string]string{"role": "db", "dc": "east"},
+		},
+		{
+			name:     "drop tags",
+			dropTags: []string{"host"},
+			expected: map[string]string{"role": "db", "dc": "east", "cpu": "cpu0"},
+		},
+		{
+			name:     "group by and drop tags",
+			groupBy:  []string{"role", "dc"},
+			dropTags: []string{"dc"},
+			expected: map[string]string{"role": "db"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &recordAggregator{}
+			ra := NewRunningAggregator(a, &AggregatorConfig{
+				Name:     "TestRunningAggregator",
+				GroupBy:  tt.groupBy,
+				DropTags: tt.dropTags,
+			})
+			require.NoError(t, ra.Init())
+
+			now := time.Now()
+			ra.UpdateWindow(now, now.Add(ra.Config.Period))
+
+			m := testutil.MustMetric("cpu",
+				map[string]string{"host": "a", "role": "db", "dc": "east", "cpu": "cpu0"},
+				map[string]interface{}{"usage_idle": 42.0},
+				now)
+			ra.Add(m)
+
+			require.Len(t, a.metrics, 1)
+			require.Equal(t, tt.expected, a.metrics[0].Tags())
+
+			// The original metric keeps its tags.
+			require.Len(t, m.TagList(), 4)
+		})
+	}
+}
+
+func TestInitGroupByError(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:    "TestRunningAggregator",
+		GroupBy: []string{"[role"},
+	})
+	require.Error(t, ra.Init())
+}
+
+func newTestAggregator() (telegraf.Aggregator, error) {
+	return &TestAggregator{}, nil
+}
+
+// makeMetricAccumulator passes the metrics through MakeMetric like the agent
+// accumulator.
+type makeMetricAccumulator struct {
+	*testutil.Accumulator
+	ra *RunningAggregator
+}
+
+func (a *makeMetricAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	m := testutil.MustMetric(measurement, tags, fields, time.Now())
+	a.AddMetric(a.ra.MakeMetric(m))
+}
+
+func sumMetric(sum int64, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric("TestMetric",
+		map[string]string{},
+		map[string]interface{}{"sum": sum},
+		tm)
+}
+
+func valueMetric(value int64, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric("RITest",
+		map[string]string{},
+		map[string]interface{}{"value": value},
+		tm)
+}
+
+func TestSlidingWindows(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: 3 * time.Second,
+		Step:   time.Second,
+	})
+	ra.Creator = newTestAggregator
+	require.NoError(t, ra.Init())
+	require.Equal(t, time.Second, ra.Period())
+	acc := &makeMetricAccumulator{Accumulator: &testutil.Accumulator{}, ra: ra}
+
+	base := time.Unix(100, 0)
+	ra.UpdateWindow(base.Add(-time.Second), base)
+
+	ra.Add(valueMetric(1, base.Add(500*time.Millisecond)))
+	ra.Add(valueMetric(2, base.Add(1500*time.Millisecond)))
+
+	// Pushes at 100s and 101s; the first window containing a metric ends at
+	// 101s.
+	ra.Push(acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+	ra.Push(acc)
+	ra.Push(acc)
+
+	// All windows containing this metric are closed.
+	dropped := ra.MetricsDropped.Get()
+	ra.Add(valueMetric(4, base.Add(-time.Second)))
+	require.Equal(t, dropped+1, ra.MetricsDropped.Get())
+
+	ra.Flush(acc)
+
+	expected := []telegraf.Metric{
+		sumMetric(1, time.Unix(101, 0)),
+		sumMetric(3, time.Unix(102, 0)),
+		sumMetric(3, time.Unix(103, 0)),
+		sumMetric(2, time.Unix(104, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestEventTimeWindows(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:            "TestRunningAggregator",
+		Period:          10 * time.Second,
+		EventTime:       true,
+		AllowedLateness: 5 * time.Second,
+	})
+	ra.Creator = newTestAggregator
+	require.NoError(t, ra.Init())
+	acc := &makeMetricAccumulator{Accumulator: &testutil.Accumulator{}, ra: ra}
+
+	// The clock does not matter for event time windows, only the alignment
+	// of the periods.
+	ra.UpdateWindow(time.Unix(0, 0), time.Unix(10, 0))
+
+	ra.Add(valueMetric(1, time.Unix(100, 0)))
+	ra.Add(valueMetric(2, time.Unix(105, 0)))
+	ra.Add(valueMetric(4, time.Unix(112, 0)))
+	ra.Push(acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	// Late but within the allowed lateness.
+	ra.Add(valueMetric(8, time.Unix(108, 0)))
+
+	// Moves the watermark past the end of the first window.
+	ra.Add(valueMetric(16, time.Unix(116, 0)))
+	dropped := ra.MetricsDropped.Get()
+	ra.Add(valueMetric(32, time.Unix(109, 0)))
+	require.Equal(t, dropped+1, ra.MetricsDropped.Get())
+
+	ra.Push(acc)
+	ra.Flush(acc)
+
+	expected := []telegraf.Metric{
+		sumMetric(11, time.Unix(110, 0)),
+		sumMetric(20, time.Unix(120, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWindowsAlignedToPeriods(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: 2 * time.Second,
+		Step:   time.Second,
+	})
+	ra.Creator = newTestAggregator
+	require.NoError(t, ra.Init())
+	acc := &makeMetricAccumulator{Accumulator: &testutil.Accumulator{}, ra: ra}
+
+	// Without round_interval the periods start when the agent starts.
+	base := time.Unix(100, 300*int64(time.Millisecond))
+	ra.UpdateWindow(base.Add(-time.Second), base)
+
+	ra.Add(valueMetric(1, base.Add(500*time.Millisecond)))
+	ra.Add(valueMetric(2, base.Add(-200*time.Millisecond)))
+	ra.Flush(acc)
+
+	expected := []telegraf.Metric{
+		sumMetric(2, base),
+		sumMetric(3, base.Add(time.Second)),
+		sumMetric(1, base.Add(2*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestInitWindowErrors(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: 10 * time.Second,
+		Step:   3 * time.Second,
+	})
+	ra.Creator = newTestAggregator
+	require.Error(t, ra.Init())
+
+	ra = NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:      "TestRunningAggregator",
+		Period:    10 * time.Second,
+		EventTime: true,
+	})
+	require.Error(t, ra.Init())
+}
+
+// recordAggregator keeps the added metrics.
+type recordAggregator struct {
+	metrics []telegraf.Metric
+}
+
+func (r *recordAggregator) Description() string       { return "" }
+func (r *recordAggregator) SampleConfig() string      { return "" }
+func (r *recordAggregator) Reset()                    { r.metrics = nil }
+func (r *recordAggregator) Push(telegraf.Accumulator) {}
+func (r *recordAggregator) Add(in telegraf.Metric)    { r.metrics = append(r.metrics, in) }
+
 type TestAggregator struct {
 	sum int64
 }
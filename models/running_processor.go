@@ -28,6 +28,20 @@ type ProcessorConfig struct {
 	Filter Filter
 }
 
+// ProcessorBranch is a chain of processors applied to the metrics selected by
+// the filter.
+type ProcessorBranch struct {
+	Filter     Filter
+	Processors RunningProcessors
+}
+
+// BranchingProcessor is implemented by processors running nested processor
+// chains.  The chains are built from the "case" and "default" tables of the
+// plugin configuration and passed to the processor before it is initialized.
+type BranchingProcessor interface {
+	SetBranches(cases []*ProcessorBranch, fallback RunningProcessors)
+}
+
 func NewRunningProcessor(processor telegraf.StreamingProcessor, config *ProcessorConfig) *RunningProcessor {
 	tags := map[string]string{"processor": config.Name}
 	if config.Alias != "" {
@@ -0,0 +1,225 @@
+package counter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/common/counters"
+)
+
+type Counter struct {
+	Fields      []string          `toml:"fields"`
+	Stats       []string          `toml:"stats"`
+	CounterSize int               `toml:"counter_size"`
+	MaxGap      internal.Duration `toml:"max_gap"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	wraparound  counters.Wraparound
+	increase    bool
+	rate        bool
+	cache       map[uint64]*series
+}
+
+// series holds the counters of one series.  The last sample of each counter
+// is kept between periods, so that the increase between the last sample of
+// a period and the first sample of the next period is not lost.
+type series struct {
+	name     string
+	tags     map[string]string
+	counters map[string]*counter
+	lastSeen time.Time
+}
+
+type counter struct {
+	last     interface{}
+	lastTime time.Time
+
+	// increase and elapsed are the sums of the differences between the
+	// samples of the period, excluding gaps.
+	increase float64
+	elapsed  time.Duration
+	samples  int
+}
+
+var sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Counter fields, glob patterns are supported.
+  fields = ["*"]
+
+  ## Values to output for each counter, "increase" for the increase within
+  ## the period and "rate" for the average increase per second.
+  # stats = ["increase", "rate"]
+
+  ## Size of the counters in bits, either 32 or 64.  When set, the increase
+  ## of a counter decreasing from the upper half of its range is counted
+  ## across the wraparound.  Otherwise a decreasing counter is taken as
+  ## restarted from zero, and its new value is added to the increase.
+  # counter_size = 0
+
+  ## Maximum time between two samples of a counter, the increase over larger
+  ## gaps is not counted.  Series that are not updated within this time are
+  ## removed.
+  # max_gap = "5m"
+`
+
+func (c *Counter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Counter) Description() string {
+	return "Compute the increase and rate of counters, handling resets."
+}
+
+func (c *Counter) Init() error {
+	stats := c.Stats
+	if len(stats) == 0 {
+		stats = []string{"increase", "rate"}
+	}
+	for _, stat := range stats {
+		switch stat {
+		case "increase":
+			c.increase = true
+		case "rate":
+			c.rate = true
+		default:
+			return fmt.Errorf("invalid stat %q", stat)
+		}
+	}
+
+	var err error
+	c.wraparound, err = counters.NewWraparound(c.CounterSize)
+	if err != nil {
+		return fmt.Errorf("counter_size: %v", err)
+	}
+
+	fields := c.Fields
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	c.fieldFilter, err = filter.Compile(fields)
+	if err != nil {
+		return fmt.Errorf("compiling fields: %v", err)
+	}
+
+	c.cache = make(map[uint64]*series)
+	return nil
+}
+
+func (c *Counter) Add(in telegraf.Metric) {
+	id := in.HashID()
+	s, ok := c.cache[id]
+	if !ok {
+		s = &series{
+			name:     in.Name(),
+			tags:     in.Tags(),
+			counters: make(map[string]*counter),
+		}
+		c.cache[id] = s
+	}
+	s.lastSeen = time.Now()
+
+	tm := in.Time()
+	for _, field := range in.FieldList() {
+		if !c.fieldFilter.Match(field.Key) || !counters.IsNumeric(field.Value) {
+			continue
+		}
+
+		cnt, ok := s.counters[field.Key]
+		if !ok {
+			s.counters[field.Key] = &counter{last: field.Value, lastTime: tm}
+			continue
+		}
+		if !tm.After(cnt.lastTime) {
+			// Out of order or duplicate sample.
+			continue
+		}
+
+		if c.MaxGap.Duration == 0 || tm.Sub(cnt.lastTime) <= c.MaxGap.Duration {
+			cnt.increase += c.delta(cnt.last, field.Value)
+			cnt.elapsed += tm.Sub(cnt.lastTime)
+			cnt.samples++
+		}
+		cnt.last = field.Value
+		cnt.lastTime = tm
+	}
+}
+
+// delta returns the increase from prev to cur.  If the counter decreased
+// and did not wrap around, it was reset and counted up from zero to cur.
+func (c *Counter) delta(prev, cur interface{}) float64 {
+	p, prevOk := counters.ToUint(prev)
+	v, curOk := counters.ToUint(cur)
+	if prevOk && curOk {
+		if diff, ok := c.wraparound.Diff(p, v); ok {
+			return float64(diff)
+		}
+		return float64(v)
+	}
+
+	pf, vf := counters.ToFloat(prev), counters.ToFloat(cur)
+	if vf >= pf {
+		return vf - pf
+	}
+	if vf < 0 {
+		return 0
+	}
+	return vf
+}
+
+func (c *Counter) Push(acc telegraf.Accumulator) {
+	for _, s := range c.cache {
+		fields := make(map[string]interface{})
+		for key, cnt := range s.counters {
+			if cnt.samples == 0 {
+				continue
+			}
+			if c.increase {
+				fields[key+"_increase"] = cnt.increase
+			}
+			if c.rate {
+				// Not extrapolated to the period bounds like Prometheus
+				// does, the time before the first sample of the period is
+				// covered by the last sample of the previous period.
+				fields[key+"_rate"] = cnt.increase / cnt.elapsed.Seconds()
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset clears the increase of the period but keeps the last samples.
+func (c *Counter) Reset() {
+	for id, s := range c.cache {
+		if c.MaxGap.Duration != 0 && time.Since(s.lastSeen) > c.MaxGap.Duration {
+			delete(c.cache, id)
+			continue
+		}
+		for _, cnt := range s.counters {
+			cnt.increase = 0
+			cnt.elapsed = 0
+			cnt.samples = 0
+		}
+	}
+}
+
+func init() {
+	aggregators.Add("counter", func() telegraf.Aggregator {
+		return &Counter{
+			MaxGap: internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
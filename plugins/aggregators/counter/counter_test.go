@@ -0,0 +1,137 @@
+package counter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newCounter() *Counter {
+	return &Counter{
+		MaxGap: internal.Duration{Duration: 5 * time.Minute},
+		Log:    testutil.Logger{},
+	}
+}
+
+func addSamples(c *Counter, start int64, values ...interface{}) {
+	for i, v := range values {
+		c.Add(testutil.MustMetric("net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes": v, "state": "up"},
+			time.Unix(start+int64(i)*10, 0),
+		))
+	}
+}
+
+func result(increase, rate float64) telegraf.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		map[string]interface{}{"bytes_increase": increase, "bytes_rate": rate},
+		time.Unix(0, 0),
+	)
+}
+
+func push(c *Counter) []telegraf.Metric {
+	var acc testutil.Accumulator
+	c.Push(&acc)
+	c.Reset()
+	return acc.GetTelegrafMetrics()
+}
+
+func TestIncrease(t *testing.T) {
+	c := newCounter()
+	require.NoError(t, c.Init())
+
+	addSamples(c, 0, int64(100), int64(150), int64(250))
+	expected := []telegraf.Metric{result(150, 7.5)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestResetWithinPeriod(t *testing.T) {
+	c := newCounter()
+	require.NoError(t, c.Init())
+
+	// The counter restarts at zero after 300 and counts up to 40.
+	addSamples(c, 0, int64(100), int64(300), int64(10), int64(40))
+	expected := []telegraf.Metric{result(240, 8)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestFloatReset(t *testing.T) {
+	c := newCounter()
+	require.NoError(t, c.Init())
+
+	addSamples(c, 0, 1.5, 3.5, 0.5)
+	expected := []telegraf.Metric{result(2.5, 0.125)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestWrap(t *testing.T) {
+	c := newCounter()
+	c.CounterSize = 32
+	require.NoError(t, c.Init())
+
+	addSamples(c, 0, uint64(4294967200), uint64(100))
+	expected := []telegraf.Metric{result(196, 19.6)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestAcrossPeriods(t *testing.T) {
+	c := newCounter()
+	require.NoError(t, c.Init())
+
+	// A single sample has no increase.
+	addSamples(c, 0, int64(100))
+	require.Empty(t, push(c))
+
+	// The increase from the last sample of the previous period is counted.
+	addSamples(c, 10, int64(200), int64(300))
+	expected := []telegraf.Metric{result(200, 10)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+
+	addSamples(c, 30, int64(400))
+	expected = []telegraf.Metric{result(100, 10)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestGap(t *testing.T) {
+	c := newCounter()
+	c.MaxGap = internal.Duration{Duration: 15 * time.Second}
+	require.NoError(t, c.Init())
+
+	addSamples(c, 0, int64(100), int64(200))
+	addSamples(c, 100, int64(1000), int64(1100))
+	expected := []telegraf.Metric{result(200, 10)}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestStats(t *testing.T) {
+	c := newCounter()
+	c.Stats = []string{"increase"}
+	c.Fields = []string{"bytes"}
+	require.NoError(t, c.Init())
+
+	addSamples(c, 0, int64(100), int64(150))
+	expected := []telegraf.Metric{
+		testutil.MustMetric("net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes_increase": 50.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, push(c), testutil.IgnoreTime())
+}
+
+func TestInitErrors(t *testing.T) {
+	c := newCounter()
+	c.Stats = []string{"sum"}
+	require.Error(t, c.Init())
+
+	c = newCounter()
+	c.CounterSize = 16
+	require.Error(t, c.Init())
+}
@@ -0,0 +1,268 @@
+package join
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	joinInner = "inner"
+	joinLeft  = "left"
+)
+
+type Source struct {
+	Measurement string   `toml:"measurement"`
+	KeyTags     []string `toml:"key_tags"`
+	Prefix      string   `toml:"prefix"`
+}
+
+type Join struct {
+	Keys    []string `toml:"keys"`
+	Join    string   `toml:"join"`
+	Sources []Source `toml:"source"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// cache holds the rows of each source by the join key and the other
+	// tags of the series.
+	cache []map[string]map[string]*row
+
+	// ambiguous holds the sources and keys already warned about.
+	ambiguous map[string]bool
+}
+
+// row is the latest data of one series of a source.
+type row struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+var sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Tags joining the metrics of the sources.
+  keys = ["device"]
+
+  ## Type of join, either "inner" to only output joined metrics if all
+  ## sources have a metric with the key, or "left" to output joined metrics
+  ## for all keys of the first source.
+  # join = "inner"
+
+  ## The measurements to join, the output metric has the name and the tags
+  ## of the first source.  The fields of each source are prefixed if a prefix
+  ## is set.  If a source uses different names for the key tags they can be
+  ## set in key_tags, in the same order as keys.
+  [[aggregators.join.source]]
+    measurement = "disk"
+    prefix = "disk_"
+
+  [[aggregators.join.source]]
+    measurement = "diskio"
+    key_tags = ["name"]
+    prefix = "io_"
+`
+
+func (j *Join) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Join) Description() string {
+	return "Join the metrics of different measurements by common tags."
+}
+
+func (j *Join) Init() error {
+	switch j.Join {
+	case "":
+		j.Join = joinInner
+	case joinInner, joinLeft:
+	default:
+		return fmt.Errorf("invalid join %q", j.Join)
+	}
+
+	if len(j.Keys) == 0 {
+		return errors.New("no keys set")
+	}
+	if len(j.Sources) < 2 {
+		return errors.New("at least two sources are required")
+	}
+
+	for i := range j.Sources {
+		s := &j.Sources[i]
+		if s.Measurement == "" {
+			return fmt.Errorf("source %d: no measurement set", i+1)
+		}
+		for _, other := range j.Sources[:i] {
+			if other.Measurement == s.Measurement {
+				return fmt.Errorf("source %d: duplicate measurement %q", i+1, s.Measurement)
+			}
+		}
+		if len(s.KeyTags) == 0 {
+			s.KeyTags = j.Keys
+		}
+		if len(s.KeyTags) != len(j.Keys) {
+			return fmt.Errorf("source %d: key_tags must have the same length as keys", i+1)
+		}
+	}
+
+	j.ambiguous = make(map[string]bool)
+	j.Reset()
+	return nil
+}
+
+func (j *Join) Add(in telegraf.Metric) {
+	for i := range j.Sources {
+		s := &j.Sources[i]
+		if in.Name() != s.Measurement {
+			continue
+		}
+
+		values := make([]string, 0, len(s.KeyTags))
+		for _, tag := range s.KeyTags {
+			v, ok := in.GetTag(tag)
+			if !ok {
+				return
+			}
+			values = append(values, v)
+		}
+		key := strings.Join(values, "\x00")
+
+		// Metrics with the same key but different other tags, such as
+		// another host, are different series and are kept apart.
+		var other []string
+		for _, tag := range in.TagList() {
+			if !contains(s.KeyTags, tag.Key) {
+				other = append(other, tag.Key+"="+tag.Value)
+			}
+		}
+		id := strings.Join(other, "\x00")
+
+		series, ok := j.cache[i][key]
+		if !ok {
+			series = make(map[string]*row)
+			j.cache[i][key] = series
+		}
+		r, ok := series[id]
+		if !ok {
+			r = &row{
+				tags:   in.Tags(),
+				fields: make(map[string]interface{}, len(in.FieldList())),
+			}
+			series[id] = r
+		}
+		for _, field := range in.FieldList() {
+			r.fields[field.Key] = field.Value
+		}
+		return
+	}
+}
+
+func (j *Join) Push(acc telegraf.Accumulator) {
+	// Output in key order so the output is stable.
+	keys := make([]string, 0, len(j.cache[0]))
+	for key := range j.cache[0] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// Rows of the other sources are only joined if they are the only
+		// series with the key, otherwise it is unknown which one belongs to
+		// the series of the first source.
+		others := make([]*row, len(j.Sources))
+		complete := true
+		for i := 1; i < len(j.Sources); i++ {
+			series := j.cache[i][key]
+			if len(series) > 1 {
+				j.warnAmbiguous(i, key)
+			}
+			if len(series) != 1 {
+				complete = false
+				continue
+			}
+			for _, r := range series {
+				others[i] = r
+			}
+		}
+		if !complete && j.Join == joinInner {
+			continue
+		}
+
+		series := j.cache[0][key]
+		ids := make([]string, 0, len(series))
+		for id := range series {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			left := series[id]
+			others[0] = left
+
+			fields := make(map[string]interface{})
+			for i, s := range j.Sources {
+				if others[i] == nil {
+					continue
+				}
+				for k, v := range others[i].fields {
+					fields[s.Prefix+k] = v
+				}
+			}
+
+			// The key tags use the names of keys instead of the names used by
+			// the first source.
+			tags := make(map[string]string, len(left.tags))
+			for k, v := range left.tags {
+				tags[k] = v
+			}
+			for n, tag := range j.Sources[0].KeyTags {
+				delete(tags, tag)
+				tags[j.Keys[n]] = left.tags[tag]
+			}
+
+			acc.AddFields(j.Sources[0].Measurement, fields, tags)
+		}
+	}
+}
+
+func (j *Join) warnAmbiguous(source int, key string) {
+	k := strconv.Itoa(source) + "\x00" + key
+	if j.ambiguous[k] {
+		return
+	}
+	j.ambiguous[k] = true
+	j.Log.Warnf("Source %q has several series with the key %q, not joining them; add the tags telling them apart to keys",
+		j.Sources[source].Measurement, strings.Replace(key, "\x00", ",", -1))
+}
+
+func (j *Join) Reset() {
+	j.cache = make([]map[string]map[string]*row, len(j.Sources))
+	for i := range j.cache {
+		j.cache[i] = make(map[string]map[string]*row)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	aggregators.Add("join", func() telegraf.Aggregator {
+		return &Join{}
+	})
+}
@@ -0,0 +1,275 @@
+package join
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newJoin(join string) *Join {
+	return &Join{
+		Keys: []string{"device"},
+		Join: join,
+		Sources: []Source{
+			{Measurement: "disk", Prefix: "disk_"},
+			{Measurement: "diskio", KeyTags: []string{"name"}, Prefix: "io_"},
+		},
+		Log: testutil.Logger{},
+	}
+}
+
+func addMetrics(j *Join) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sda1", "path": "/"},
+			map[string]interface{}{"used": 40, "total": 100},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sdb1", "path": "/data"},
+			map[string]interface{}{"used": 10, "total": 200},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("diskio",
+			map[string]string{"host": "a", "name": "sda1"},
+			map[string]interface{}{"reads": 5},
+			time.Unix(0, 0),
+		),
+		// The latest value of a field is used.
+		testutil.MustMetric("diskio",
+			map[string]string{"host": "a", "name": "sda1"},
+			map[string]interface{}{"reads": 7, "writes": 3},
+			time.Unix(10, 0),
+		),
+		testutil.MustMetric("diskio",
+			map[string]string{"host": "a", "name": "sdc1"},
+			map[string]interface{}{"reads": 1},
+			time.Unix(0, 0),
+		),
+		// Ignored, not a source.
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "device": "sda1"},
+			map[string]interface{}{"usage_idle": 1},
+			time.Unix(0, 0),
+		),
+	}
+	for _, m := range metrics {
+		j.Add(m)
+	}
+}
+
+func TestInnerJoin(t *testing.T) {
+	j := newJoin("inner")
+	require.NoError(t, j.Init())
+	addMetrics(j)
+
+	var acc testutil.Accumulator
+	j.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sda1", "path": "/"},
+			map[string]interface{}{
+				"disk_used":  40,
+				"disk_total": 100,
+				"io_reads":   7,
+				"io_writes":  3,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestLeftJoin(t *testing.T) {
+	j := newJoin("left")
+	require.NoError(t, j.Init())
+	addMetrics(j)
+
+	var acc testutil.Accumulator
+	j.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sda1", "path": "/"},
+			map[string]interface{}{
+				"disk_used":  40,
+				"disk_total": 100,
+				"io_reads":   7,
+				"io_writes":  3,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sdb1", "path": "/data"},
+			map[string]interface{}{
+				"disk_used":  10,
+				"disk_total": 200,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestKeyTagsRenamed(t *testing.T) {
+	j := &Join{
+		Keys: []string{"container_id"},
+		Sources: []Source{
+			{Measurement: "procstat", KeyTags: []string{"cid"}},
+			{Measurement: "docker"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, j.Init())
+
+	j.Add(testutil.MustMetric("procstat",
+		map[string]string{"cid": "abc"},
+		map[string]interface{}{"cpu_usage": 1.5},
+		time.Unix(0, 0),
+	))
+	j.Add(testutil.MustMetric("docker",
+		map[string]string{"container_id": "abc"},
+		map[string]interface{}{"limit": 4},
+		time.Unix(0, 0),
+	))
+
+	var acc testutil.Accumulator
+	j.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("procstat",
+			map[string]string{"container_id": "abc"},
+			map[string]interface{}{"cpu_usage": 1.5, "limit": 4},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestSeriesKeptApart(t *testing.T) {
+	j := &Join{
+		Keys: []string{"device"},
+		Join: "left",
+		Sources: []Source{
+			{Measurement: "disk", Prefix: "disk_"},
+			{Measurement: "diskio", KeyTags: []string{"name"}, Prefix: "io_"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, j.Init())
+
+	for _, host := range []string{"b", "a"} {
+		j.Add(testutil.MustMetric("disk",
+			map[string]string{"host": host, "device": "sda1", "path": "/"},
+			map[string]interface{}{"used": host},
+			time.Unix(0, 0),
+		))
+		j.Add(testutil.MustMetric("diskio",
+			map[string]string{"host": host, "name": "sda1"},
+			map[string]interface{}{"reads": host},
+			time.Unix(0, 0),
+		))
+	}
+
+	var acc testutil.Accumulator
+	j.Push(&acc)
+
+	// The rows of both hosts are output separately, the diskio rows are
+	// ambiguous for the key and are not joined to either.
+	expected := []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sda1", "path": "/"},
+			map[string]interface{}{"disk_used": "a"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("disk",
+			map[string]string{"host": "b", "device": "sda1", "path": "/"},
+			map[string]interface{}{"disk_used": "b"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// With host as a key both are joined.
+	j.Keys = []string{"device", "host"}
+	j.Sources[0].KeyTags = nil
+	j.Sources[1].KeyTags = []string{"name", "host"}
+	require.NoError(t, j.Init())
+	for _, host := range []string{"b", "a"} {
+		j.Add(testutil.MustMetric("disk",
+			map[string]string{"host": host, "device": "sda1", "path": "/"},
+			map[string]interface{}{"used": host},
+			time.Unix(0, 0),
+		))
+		j.Add(testutil.MustMetric("diskio",
+			map[string]string{"host": host, "name": "sda1"},
+			map[string]interface{}{"reads": host},
+			time.Unix(0, 0),
+		))
+	}
+
+	acc.ClearMetrics()
+	j.Push(&acc)
+
+	expected = []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"host": "a", "device": "sda1", "path": "/"},
+			map[string]interface{}{"disk_used": "a", "io_reads": "a"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("disk",
+			map[string]string{"host": "b", "device": "sda1", "path": "/"},
+			map[string]interface{}{"disk_used": "b", "io_reads": "b"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestReset(t *testing.T) {
+	j := newJoin("left")
+	require.NoError(t, j.Init())
+	addMetrics(j)
+	j.Reset()
+
+	var acc testutil.Accumulator
+	j.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		j    *Join
+	}{
+		{
+			name: "invalid join",
+			j:    &Join{Keys: []string{"a"}, Join: "outer", Sources: []Source{{Measurement: "x"}, {Measurement: "y"}}},
+		},
+		{
+			name: "no keys",
+			j:    &Join{Sources: []Source{{Measurement: "x"}, {Measurement: "y"}}},
+		},
+		{
+			name: "one source",
+			j:    &Join{Keys: []string{"a"}, Sources: []Source{{Measurement: "x"}}},
+		},
+		{
+			name: "duplicate source",
+			j:    &Join{Keys: []string{"a"}, Sources: []Source{{Measurement: "x"}, {Measurement: "x"}}},
+		},
+		{
+			name: "key tags length",
+			j:    &Join{Keys: []string{"a"}, Sources: []Source{{Measurement: "x", KeyTags: []string{"a", "b"}}, {Measurement: "y"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.j.Init())
+		})
+	}
+}
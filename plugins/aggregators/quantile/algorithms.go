@@ -0,0 +1,45 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+
+	"github.com/caio/go-tdigest"
+)
+
+func newTDigest(compression uint32) (algorithm, error) {
+	return tdigest.New(tdigest.Compression(compression))
+}
+
+// exact keeps all values and computes the quantiles by linear interpolation
+// between the closest ranks, known as method R7 or the default of numpy and
+// R.
+type exact struct {
+	values []float64
+	sorted bool
+}
+
+func (e *exact) Add(value float64) error {
+	e.values = append(e.values, value)
+	e.sorted = false
+	return nil
+}
+
+func (e *exact) Quantile(q float64) float64 {
+	n := len(e.values)
+	if n == 0 {
+		return math.NaN()
+	}
+	if !e.sorted {
+		sort.Float64s(e.values)
+		e.sorted = true
+	}
+
+	h := float64(n-1) * q
+	lo := math.Floor(h)
+	i := int(lo)
+	if i >= n-1 {
+		return e.values[n-1]
+	}
+	return e.values[i] + (h-lo)*(e.values[i+1]-e.values[i])
+}
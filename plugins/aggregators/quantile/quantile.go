@@ -0,0 +1,210 @@
+package quantile
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	algorithmTDigest = "t-digest"
+	algorithmExact   = "exact"
+)
+
+type Quantile struct {
+	Quantiles      []float64            `toml:"quantiles"`
+	FieldQuantiles map[string][]float64 `toml:"field_quantiles"`
+	Algorithm      string               `toml:"algorithm"`
+	Compression    float64              `toml:"compression"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	cache    map[uint64]aggregate
+	newAlgo  func() (algorithm, error)
+	suffixes map[float64]string
+	defaultQ []float64
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]algorithm
+}
+
+// algorithm estimates the quantiles of the values added to it.
+type algorithm interface {
+	Add(value float64) error
+	Quantile(q float64) float64
+}
+
+var sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1].
+  # quantiles = [0.5, 0.95, 0.99]
+
+  ## Quantiles for specific fields, overriding the quantiles above.
+  # [aggregators.quantile.field_quantiles]
+  #   response_time = [0.5, 0.9, 0.99, 0.999]
+
+  ## Type of aggregation algorithm, either "t-digest" for an approximation
+  ## using constant memory, or "exact" to keep all values of the period.
+  # algorithm = "t-digest"
+
+  ## Compression for the t-digest, at least 1.  Larger values are more
+  ## accurate but use more memory and CPU.
+  # compression = 100.0
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Init() error {
+	switch q.Algorithm {
+	case "", algorithmTDigest:
+		if q.Compression == 0 {
+			q.Compression = 100
+		}
+		if q.Compression < 1 {
+			return fmt.Errorf("compression must be at least 1, got %v", q.Compression)
+		}
+		compression := uint32(q.Compression)
+		q.newAlgo = func() (algorithm, error) { return newTDigest(compression) }
+	case algorithmExact:
+		q.newAlgo = func() (algorithm, error) { return &exact{}, nil }
+	default:
+		return fmt.Errorf("unknown algorithm %q", q.Algorithm)
+	}
+
+	q.defaultQ = q.Quantiles
+	if len(q.defaultQ) == 0 {
+		q.defaultQ = []float64{0.5, 0.95, 0.99}
+	}
+
+	q.suffixes = make(map[float64]string)
+	if err := q.addSuffixes(q.defaultQ); err != nil {
+		return err
+	}
+	for field, quantiles := range q.FieldQuantiles {
+		if len(quantiles) == 0 {
+			return fmt.Errorf("field_quantiles for %q must not be empty", field)
+		}
+		if err := q.addSuffixes(quantiles); err != nil {
+			return err
+		}
+	}
+
+	q.Reset()
+	return nil
+}
+
+// addSuffixes validates the quantiles and creates the field name suffixes,
+// for example "_p99" for 0.99 or "_p99_9" for 0.999.  Quantiles with the
+// same suffix would overwrite each others field and are rejected.
+func (q *Quantile) addSuffixes(quantiles []float64) error {
+	seen := make(map[string]float64, len(quantiles))
+	for _, v := range quantiles {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("quantile %v out of range [0,1]", v)
+		}
+		// Round to the precision of the suffix, 0.07*100 is 7.000000000000001.
+		p := strconv.FormatFloat(math.Round(v*1e4)/1e2, 'f', -1, 64)
+		suffix := "_p" + strings.Replace(p, ".", "_", -1)
+		if other, ok := seen[suffix]; ok {
+			return fmt.Errorf("quantiles %v and %v have the same field suffix %q", other, v, suffix)
+		}
+		seen[suffix] = v
+		q.suffixes[v] = suffix
+	}
+	return nil
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]algorithm),
+		}
+		q.cache[id] = a
+	}
+
+	for _, field := range in.FieldList() {
+		v, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+
+		algo, ok := a.fields[field.Key]
+		if !ok {
+			var err error
+			algo, err = q.newAlgo()
+			if err != nil {
+				q.Log.Errorf("Creating aggregation for field %q failed: %v", field.Key, err)
+				continue
+			}
+			a.fields[field.Key] = algo
+		}
+
+		if err := algo.Add(v); err != nil {
+			q.Log.Errorf("Adding value of field %q failed: %v", field.Key, err)
+		}
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, a := range q.cache {
+		fields := make(map[string]interface{})
+		for key, algo := range a.fields {
+			quantiles, ok := q.FieldQuantiles[key]
+			if !ok {
+				quantiles = q.defaultQ
+			}
+			for _, v := range quantiles {
+				fields[key+q.suffixes[v]] = algo.Quantile(v)
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return &Quantile{Compression: 100}
+	})
+}
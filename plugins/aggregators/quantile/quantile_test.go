@@ -0,0 +1,173 @@
+package quantile
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func addValues(q *Quantile, name string, values ...interface{}) {
+	for _, v := range values {
+		q.Add(testutil.MustMetric(name,
+			map[string]string{"host": "a"},
+			map[string]interface{}{"latency": v, "status": "ok"},
+			time.Unix(0, 0),
+		))
+	}
+}
+
+func TestExact(t *testing.T) {
+	q := &Quantile{
+		Algorithm: "exact",
+		Quantiles: []float64{0, 0.25, 0.5, 0.999, 1},
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, q.Init())
+
+	addValues(q, "ping", 4.0, int64(1), uint64(3), 2.0, 5.0)
+
+	var acc testutil.Accumulator
+	q.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ping",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"latency_p0":    1.0,
+				"latency_p25":   2.0,
+				"latency_p50":   3.0,
+				"latency_p99_9": 4.996,
+				"latency_p100":  5.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestTDigest(t *testing.T) {
+	q := &Quantile{Log: testutil.Logger{}}
+	require.NoError(t, q.Init())
+
+	for i := 1; i <= 1000; i++ {
+		addValues(q, "http", float64(i))
+	}
+
+	var acc testutil.Accumulator
+	q.Push(&acc)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	fields := metrics[0].Fields()
+	require.Len(t, fields, 3)
+	require.InDelta(t, 500.0, fields["latency_p50"], 5)
+	require.InDelta(t, 950.0, fields["latency_p95"], 5)
+	require.InDelta(t, 990.0, fields["latency_p99"], 5)
+}
+
+func TestFieldQuantiles(t *testing.T) {
+	q := &Quantile{
+		Algorithm:      "exact",
+		Quantiles:      []float64{0.5},
+		FieldQuantiles: map[string][]float64{"latency": {0.9}},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, q.Init())
+
+	for i := 1; i <= 11; i++ {
+		q.Add(testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{"latency": float64(i), "size": int64(i)},
+			time.Unix(0, 0),
+		))
+	}
+
+	var acc testutil.Accumulator
+	q.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{
+				"latency_p90": 10.0,
+				"size_p50":    6.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestReset(t *testing.T) {
+	q := &Quantile{Algorithm: "exact", Log: testutil.Logger{}}
+	require.NoError(t, q.Init())
+
+	addValues(q, "ping", 1.0)
+	q.Reset()
+
+	var acc testutil.Accumulator
+	q.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestExactEmpty(t *testing.T) {
+	e := &exact{}
+	require.True(t, math.IsNaN(e.Quantile(0.5)))
+}
+
+func TestSuffixes(t *testing.T) {
+	q := &Quantile{Quantiles: []float64{0.07, 0.29, 0.5, 0.57, 0.999}}
+	require.NoError(t, q.Init())
+	require.Equal(t, map[float64]string{
+		0.07:  "_p7",
+		0.29:  "_p29",
+		0.5:   "_p50",
+		0.57:  "_p57",
+		0.999: "_p99_9",
+	}, q.suffixes)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Quantile
+	}{
+		{
+			name:   "unknown algorithm",
+			plugin: &Quantile{Algorithm: "hdr"},
+		},
+		{
+			name:   "negative compression",
+			plugin: &Quantile{Compression: -1},
+		},
+		{
+			name:   "compression below one",
+			plugin: &Quantile{Compression: 0.5},
+		},
+		{
+			name:   "quantile out of range",
+			plugin: &Quantile{Quantiles: []float64{95}},
+		},
+		{
+			name:   "same suffix",
+			plugin: &Quantile{Quantiles: []float64{0.99999, 1}},
+		},
+		{
+			name:   "same suffix in field quantiles",
+			plugin: &Quantile{FieldQuantiles: map[string][]float64{"latency": {0.5, 0.50001}}},
+		},
+		{
+			name:   "empty field quantiles",
+			plugin: &Quantile{FieldQuantiles: map[string][]float64{"latency": {}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
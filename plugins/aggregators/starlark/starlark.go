@@ -0,0 +1,159 @@
+package starlark
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"go.starlark.net/starlark"
+)
+
+const (
+	description  = "Aggregate metrics using a Starlark script"
+	sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## The Starlark source can be set as a string in this configuration file, or
+  ## by referencing a file containing the script.  Only one source or script
+  ## should be set at once.
+  ##
+  ## Source of the Starlark script.
+  source = '''
+def add(state, metric):
+  state["last"] = metric
+
+def push(state):
+  return state.get("last")
+
+def reset(state):
+  state.clear()
+'''
+
+  ## File containing a Starlark script.
+  # script = "/usr/local/bin/myscript.star"
+
+  ## Values that are predeclared in the script as global constants.
+  # [aggregators.starlark.constants]
+  #   max_size = 10
+  #   threshold = 0.75
+  #   default_name = "Julia"
+`
+)
+
+type Starlark struct {
+	Source    string                 `toml:"source"`
+	Script    string                 `toml:"script"`
+	Constants map[string]interface{} `toml:"constants"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	program   *common.Program
+	addFunc   *starlark.Function
+	pushFunc  *starlark.Function
+	resetFunc *starlark.Function
+}
+
+func (s *Starlark) Init() error {
+	var err error
+	s.program, err = common.Load(&common.Config{
+		Name:      "aggregator.starlark",
+		Source:    s.Source,
+		Script:    s.Script,
+		Constants: s.Constants,
+		Log:       s.Log,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.addFunc, err = s.program.Function("add", 2)
+	if err != nil {
+		return err
+	}
+	s.pushFunc, err = s.program.Function("push", 1)
+	if err != nil {
+		return err
+	}
+	s.resetFunc, err = s.program.Function("reset", 1)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Starlark) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Starlark) Description() string {
+	return description
+}
+
+// Add calls the add function.  The metric is owned by the aggregator, so
+// unlike the processor a new wrapper is used for every call and the script
+// may keep it in the state.
+func (s *Starlark) Add(in telegraf.Metric) {
+	m := &common.Metric{}
+	m.Wrap(in)
+
+	args := starlark.Tuple{s.program.State(), m}
+	if _, err := s.program.Call(s.addFunc, args); err != nil {
+		s.Log.Errorf("Error calling add: %v", err)
+	}
+}
+
+// Push calls the push function, which may return None, a metric or a list
+// of metrics.
+func (s *Starlark) Push(acc telegraf.Accumulator) {
+	rv, err := s.program.Call(s.pushFunc, starlark.Tuple{s.program.State()})
+	if err != nil {
+		s.Log.Errorf("Error calling push: %v", err)
+		return
+	}
+
+	// Preserve timestamp of the returned metrics
+	acc.SetPrecision(time.Nanosecond)
+
+	switch rv := rv.(type) {
+	case *starlark.List:
+		iter := rv.Iterate()
+		defer iter.Done()
+		var v starlark.Value
+		for iter.Next(&v) {
+			switch v := v.(type) {
+			case *common.Metric:
+				s.addMetric(acc, v)
+			default:
+				s.Log.Errorf("Invalid type returned in list: %s", v.Type())
+			}
+		}
+	case *common.Metric:
+		s.addMetric(acc, rv)
+	case starlark.NoneType:
+	default:
+		s.Log.Errorf("Invalid type returned: %s", rv.Type())
+	}
+}
+
+// addMetric adds a copy of the metric, the script may still hold a
+// reference in the state and push it again.
+func (s *Starlark) addMetric(acc telegraf.Accumulator, m *common.Metric) {
+	acc.AddMetric(m.Unwrap().Copy())
+}
+
+func (s *Starlark) Reset() {
+	if _, err := s.program.Call(s.resetFunc, starlark.Tuple{s.program.State()}); err != nil {
+		s.Log.Errorf("Error calling reset: %v", err)
+	}
+}
+
+func init() {
+	aggregators.Add("starlark", func() telegraf.Aggregator {
+		return &Starlark{}
+	})
+}
@@ -0,0 +1,157 @@
+package starlark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name:   "no functions",
+			source: `x = 1`,
+		},
+		{
+			name: "missing reset",
+			source: `
+def add(state, metric):
+  pass
+def push(state):
+  pass
+`,
+		},
+		{
+			name: "add must take two args",
+			source: `
+def add(metric):
+  pass
+def push(state):
+  pass
+def reset(state):
+  pass
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Starlark{
+				Source: tt.source,
+				Log:    testutil.Logger{},
+			}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
+
+func TestWeightedAverage(t *testing.T) {
+	plugin := &Starlark{
+		Script: "testdata/weighted_average.star",
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	plugin.Add(testutil.MustMetric("req",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"value": 10.0, "weight": 1},
+		time.Unix(0, 0),
+	))
+	plugin.Add(testutil.MustMetric("req",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"value": 20.0, "weight": 3},
+		time.Unix(10, 0),
+	))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("req_weighted",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"mean": 17.5},
+			time.Unix(10, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// After a reset nothing is pushed until new metrics arrive.
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestPushStoredMetric(t *testing.T) {
+	plugin := &Starlark{
+		Source: `
+def add(state, metric):
+  if metric.fields["value"] > state.get("max", 0):
+    state["max"] = metric.fields["value"]
+    state["metric"] = metric
+
+def push(state):
+  return state.get("metric")
+
+def reset(state):
+  pass
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	for _, v := range []int64{3, 7, 5} {
+		plugin.Add(testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{"value": v},
+			time.Unix(v, 0),
+		))
+	}
+
+	// The stored metric is kept across windows since reset does not clear
+	// the state, each push emits its own copy.
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	plugin.Reset()
+	plugin.Push(&acc)
+
+	m := testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 7},
+		time.Unix(7, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m, m}, acc.GetTelegrafMetrics())
+}
+
+func TestScriptError(t *testing.T) {
+	plugin := &Starlark{
+		Source: `
+def add(state, metric):
+  state["sum"] = state.get("sum", 0) + metric.fields["missing"]
+
+def push(state):
+  return None
+
+def reset(state):
+  state.clear()
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Errors are logged and do not stop the aggregator.
+	plugin.Add(testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
@@ -0,0 +1,80 @@
+package counters
+
+import (
+	"fmt"
+	"math"
+)
+
+// Wraparound computes the increase of counters of a fixed size, which wrap
+// around to zero after their maximum value.
+type Wraparound struct {
+	max uint64
+}
+
+// NewWraparound returns the Wraparound for counters of the size in bits,
+// either 32 or 64.  A size of 0 is for counters of unknown size, which are
+// never assumed to wrap around.
+func NewWraparound(bits int) (Wraparound, error) {
+	switch bits {
+	case 0:
+		return Wraparound{}, nil
+	case 32:
+		return Wraparound{max: math.MaxUint32}, nil
+	case 64:
+		return Wraparound{max: math.MaxUint64}, nil
+	default:
+		return Wraparound{}, fmt.Errorf("invalid counter size %d, must be 32 or 64", bits)
+	}
+}
+
+// Diff returns cur - prev.  If the counter decreased from the upper half of
+// its range it is assumed to have wrapped around, otherwise a decrease is a
+// counter reset and false is returned.
+func (w Wraparound) Diff(prev, cur uint64) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if w.max == 0 || prev > w.max || prev < w.max/2 {
+		return 0, false
+	}
+	return w.max - prev + cur + 1, true
+}
+
+// IsNumeric checks if the field value is a number.
+func IsNumeric(v interface{}) bool {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToFloat converts a numeric field value, other values are 0.
+func ToFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// ToUint converts an integer counter, negative values are invalid.
+func ToUint(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
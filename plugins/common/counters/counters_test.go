@@ -0,0 +1,53 @@
+package counters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     int
+		prev     uint64
+		cur      uint64
+		expected uint64
+		ok       bool
+	}{
+		{name: "increase", bits: 0, prev: 10, cur: 15, expected: 5, ok: true},
+		{name: "reset unknown size", bits: 0, prev: math.MaxUint32, cur: 5, ok: false},
+		{name: "wrap 32", bits: 32, prev: math.MaxUint32 - 1, cur: 3, expected: 5, ok: true},
+		{name: "reset from lower half", bits: 32, prev: 1000, cur: 5, ok: false},
+		{name: "above range", bits: 32, prev: math.MaxUint32 + 1, cur: 5, ok: false},
+		{name: "wrap 64", bits: 64, prev: math.MaxUint64, cur: 0, expected: 1, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWraparound(tt.bits)
+			require.NoError(t, err)
+			diff, ok := w.Diff(tt.prev, tt.cur)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, diff)
+		})
+	}
+
+	_, err := NewWraparound(16)
+	require.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	require.True(t, IsNumeric(int64(1)))
+	require.False(t, IsNumeric("1"))
+	require.Equal(t, 2.5, ToFloat(2.5))
+	require.Equal(t, 3.0, ToFloat(uint64(3)))
+
+	v, ok := ToUint(int64(4))
+	require.True(t, ok)
+	require.Equal(t, uint64(4), v)
+	_, ok = ToUint(int64(-1))
+	require.False(t, ok)
+	_, ok = ToUint(1.5)
+	require.False(t, ok)
+}
@@ -0,0 +1,485 @@
+package starlark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// newModules returns the built-in modules that can be loaded by name, for
+// example using load("json.star", "json").
+func newModules(log telegraf.Logger) map[string]starlark.StringDict {
+	return map[string]starlark.StringDict{
+		"json.star":    {"json": newJSONModule()},
+		"math.star":    {"math": newMathModule()},
+		"time.star":    {"time": newTimeModule()},
+		"logging.star": {"log": newLoggingModule(log)},
+	}
+}
+
+// --- json module ---
+
+func newJSONModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "json",
+		Members: starlark.StringDict{
+			"encode": starlark.NewBuiltin("json.encode", jsonEncode),
+			"decode": starlark.NewBuiltin("json.decode", jsonDecode),
+		},
+	}
+}
+
+func jsonEncode(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &value); err != nil {
+		return nil, err
+	}
+
+	v, err := toGo(value)
+	if err != nil {
+		return nil, nameErr(b, err)
+	}
+	octets, err := json.Marshal(v)
+	if err != nil {
+		return nil, nameErr(b, err)
+	}
+	return starlark.String(octets), nil
+}
+
+func jsonDecode(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return nil, err
+	}
+
+	v, err := decodeJSON([]byte(s))
+	if err != nil {
+		return nil, nameErr(b, err)
+	}
+	return toStarlark(v)
+}
+
+// decodeJSON unmarshals the document keeping integers distinct from floats.
+func decodeJSON(octets []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(octets))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// toStarlark converts decoded JSON or TOML values to Starlark values.
+func toStarlark(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return starlark.MakeInt64(n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case []interface{}:
+		elems := make([]starlark.Value, 0, len(v))
+		for _, e := range v {
+			sv, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, sv)
+		}
+		return starlark.NewList(elems), nil
+	case map[string]interface{}:
+		dict := starlark.NewDict(len(v))
+		for k, e := range v {
+			sv, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(k), sv); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	default:
+		sv, err := asStarlarkValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported type %T", value)
+		}
+		return sv, nil
+	}
+}
+
+// toGo converts a Starlark value to a value that can be encoded as JSON.
+func toGo(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.List:
+		elems := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := toGo(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, e)
+		}
+		return elems, nil
+	case starlark.Tuple:
+		elems := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			ge, err := toGo(e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, ge)
+		}
+		return elems, nil
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict key must be a string, got %s", item[0].Type())
+			}
+			e, err := toGo(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = e
+		}
+		return m, nil
+	default:
+		gv, err := asGoValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %s", value.Type())
+		}
+		return gv, nil
+	}
+}
+
+// --- math module ---
+
+func newMathModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "math",
+		Members: starlark.StringDict{
+			"ceil":  mathFunc("math.ceil", math.Ceil),
+			"floor": mathFunc("math.floor", math.Floor),
+			"round": mathFunc("math.round", math.Round),
+			"fabs":  mathFunc("math.fabs", math.Abs),
+			"sqrt":  mathFunc("math.sqrt", math.Sqrt),
+			"exp":   mathFunc("math.exp", math.Exp),
+			"log":   mathFunc("math.log", math.Log),
+			"log2":  mathFunc("math.log2", math.Log2),
+			"log10": mathFunc("math.log10", math.Log10),
+			"pow": starlark.NewBuiltin("math.pow", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				var x, y starlark.Value
+				if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil {
+					return nil, err
+				}
+				fx, ok := starlark.AsFloat(x)
+				if !ok {
+					return nil, nameErr(b, fmt.Sprintf("got %s, want number", x.Type()))
+				}
+				fy, ok := starlark.AsFloat(y)
+				if !ok {
+					return nil, nameErr(b, fmt.Sprintf("got %s, want number", y.Type()))
+				}
+				return starlark.Float(math.Pow(fx, fy)), nil
+			}),
+			"isnan": mathPredicate("math.isnan", math.IsNaN),
+			"isinf": mathPredicate("math.isinf", func(x float64) bool { return math.IsInf(x, 0) }),
+			"pi":    starlark.Float(math.Pi),
+			"e":     starlark.Float(math.E),
+			"inf":   starlark.Float(math.Inf(1)),
+			"nan":   starlark.Float(math.NaN()),
+		},
+	}
+}
+
+func mathFunc(name string, fn func(float64) float64) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x); err != nil {
+			return nil, err
+		}
+		f, ok := starlark.AsFloat(x)
+		if !ok {
+			return nil, nameErr(b, fmt.Sprintf("got %s, want number", x.Type()))
+		}
+		return starlark.Float(fn(f)), nil
+	})
+}
+
+func mathPredicate(name string, fn func(float64) bool) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x); err != nil {
+			return nil, err
+		}
+		f, ok := starlark.AsFloat(x)
+		if !ok {
+			return nil, nameErr(b, fmt.Sprintf("got %s, want number", x.Type()))
+		}
+		return starlark.Bool(fn(f)), nil
+	})
+}
+
+// --- time module ---
+
+// newTimeModule returns the time module, times are integers in nanoseconds
+// since the Unix epoch like the metric time.
+func newTimeModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "time",
+		Members: starlark.StringDict{
+			"now":         starlark.NewBuiltin("time.now", timeNow),
+			"parse":       starlark.NewBuiltin("time.parse", timeParse),
+			"format":      starlark.NewBuiltin("time.format", timeFormat),
+			"nanosecond":  starlark.MakeInt64(int64(time.Nanosecond)),
+			"microsecond": starlark.MakeInt64(int64(time.Microsecond)),
+			"millisecond": starlark.MakeInt64(int64(time.Millisecond)),
+			"second":      starlark.MakeInt64(int64(time.Second)),
+			"minute":      starlark.MakeInt64(int64(time.Minute)),
+			"hour":        starlark.MakeInt64(int64(time.Hour)),
+		},
+	}
+}
+
+func timeNow(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt64(time.Now().UnixNano()), nil
+}
+
+// timeParse parses the value using a Go "reference time" layout, values
+// without a zone are interpreted as UTC.
+func timeParse(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var layout, value string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &layout, &value); err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil, nameErr(b, err)
+	}
+	return starlark.MakeInt64(t.UnixNano()), nil
+}
+
+// timeFormat formats the time using a Go "reference time" layout in UTC.
+func timeFormat(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var ns starlark.Int
+	var layout string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &ns, &layout); err != nil {
+		return nil, err
+	}
+	n, ok := ns.Int64()
+	if !ok {
+		return nil, nameErr(b, "time out of range")
+	}
+	return starlark.String(time.Unix(0, n).UTC().Format(layout)), nil
+}
+
+// --- logging module ---
+
+func newLoggingModule(log telegraf.Logger) *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "log",
+		Members: starlark.StringDict{
+			"debug": logFunc("log.debug", log.Debug),
+			"info":  logFunc("log.info", log.Info),
+			"warn":  logFunc("log.warn", log.Warn),
+			"error": logFunc("log.error", log.Error),
+		},
+	}
+}
+
+func logFunc(name string, fn func(args ...interface{})) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var msg string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &msg); err != nil {
+			return nil, err
+		}
+		fn(msg)
+		return starlark.None, nil
+	})
+}
+
+// --- constants ---
+
+// constantsDict converts the constants table from the configuration into
+// predeclared values.
+func constantsDict(constants map[string]interface{}) (starlark.StringDict, error) {
+	dict := make(starlark.StringDict, len(constants))
+	for k, c := range constants {
+		v, err := toStarlark(c)
+		if err != nil {
+			return nil, fmt.Errorf("constant %q: %v", k, err)
+		}
+		dict[k] = v
+	}
+	return dict, nil
+}
+
+// --- load ---
+
+var errLoadCycle = errors.New("cycle in load graph")
+
+// loadEntry caches the result of loading a file, a nil entry marks a file
+// that is currently being loaded.
+type loadEntry struct {
+	globals starlark.StringDict
+	err     error
+}
+
+// load implements the load statement.  Built-in modules are found by name,
+// other modules are read from files relative to the directory of the
+// script, or the working directory when the source is set inline.
+func (p *Program) load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	if m, ok := p.modules[module]; ok {
+		return m, nil
+	}
+
+	path := module
+	if !filepath.IsAbs(path) && p.script != "" {
+		path = filepath.Join(filepath.Dir(p.script), module)
+	}
+
+	e, ok := p.loaded[path]
+	if ok && e == nil {
+		return nil, errLoadCycle
+	}
+	if e == nil {
+		p.loaded[path] = nil
+		globals, err := starlark.ExecFile(p.newThread(path), path, nil, p.builtins)
+		if err == nil {
+			globals.Freeze()
+		}
+		e = &loadEntry{globals: globals, err: err}
+		p.loaded[path] = e
+	}
+	return e.globals, e.err
+}
+
+// --- state ---
+
+// readState loads the state saved by a previous run, a missing file is not
+// an error.
+func readState(path string, state *starlark.Dict) error {
+	octets, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	v, err := decodeJSON(octets)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("state must be a JSON object")
+	}
+	for k, e := range m {
+		sv, err := toStarlark(e)
+		if err != nil {
+			return err
+		}
+		if err := state.SetKey(starlark.String(k), sv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeState saves the state, the file is replaced atomically.  Values that
+// cannot be encoded are left out and logged, so the rest is still saved.
+func writeState(path string, state *starlark.Dict, log telegraf.Logger) error {
+	m := make(map[string]interface{}, state.Len())
+	for _, item := range state.Items() {
+		k, ok := starlark.AsString(item[0])
+		if !ok {
+			log.Errorf("Cannot save state key %s, keys must be strings", item[0].String())
+			continue
+		}
+		v, err := toGo(item[1])
+		if err != nil {
+			log.Errorf("Cannot save state key %q: %v", k, err)
+			continue
+		}
+		m[k] = v
+	}
+	octets, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, octets, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// stateDict is the state dict as seen by scripts, it rejects storing
+// metrics as they can neither be saved nor kept, the metric passed to apply
+// is reused for the next call.
+type stateDict struct {
+	*starlark.Dict
+}
+
+func (d *stateDict) SetKey(k, v starlark.Value) error {
+	if hasMetric(v) {
+		return errors.New("metrics cannot be stored in the state, copy the values instead")
+	}
+	return d.Dict.SetKey(k, v)
+}
+
+// hasMetric reports if the value is or contains a metric.
+func hasMetric(v starlark.Value) bool {
+	switch v := v.(type) {
+	case *Metric:
+		return true
+	case *starlark.List:
+		for i := 0; i < v.Len(); i++ {
+			if hasMetric(v.Index(i)) {
+				return true
+			}
+		}
+	case starlark.Tuple:
+		for _, e := range v {
+			if hasMetric(e) {
+				return true
+			}
+		}
+	case *starlark.Dict:
+		for _, item := range v.Items() {
+			if hasMetric(item[0]) || hasMetric(item[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+)
+
+// Config holds the options shared by the Starlark plugins.
+type Config struct {
+	// Name is used for the script in error messages when the source is set
+	// inline.
+	Name string
+	// Source is the script as a string, only one of Source and Script may
+	// be set.
+	Source string
+	// Script is the path of the script file.
+	Script string
+	// StateFile is used to save the state dict and to restore it when
+	// loading the program, it is optional.
+	StateFile string
+	// StateSaveInterval is the minimum time between saving the state after
+	// calls, so the state is not lost if Telegraf crashes.  The state is
+	// saved after every call if zero, and always on Close.
+	StateSaveInterval time.Duration
+	// Constants are predeclared as globals.
+	Constants map[string]interface{}
+
+	Log telegraf.Logger
+}
+
+// Program is a Starlark script executed with the Telegraf builtins,
+// constants and the state dict predeclared, and the built-in modules
+// available to load.
+type Program struct {
+	log          telegraf.Logger
+	script       string
+	stateFile    string
+	saveInterval time.Duration
+	lastSave     time.Time
+
+	builtins starlark.StringDict
+	modules  map[string]starlark.StringDict
+	loaded   map[string]*loadEntry
+	state    *starlark.Dict
+	thread   *starlark.Thread
+	globals  starlark.StringDict
+}
+
+// Load executes the top level of the script.  The globals are frozen
+// afterwards; values that must be kept between calls are stored in the
+// state dict.
+func Load(c *Config) (*Program, error) {
+	if c.Source == "" && c.Script == "" {
+		return nil, errors.New("one of source or script must be set")
+	}
+	if c.Source != "" && c.Script != "" {
+		return nil, errors.New("both source or script cannot be set")
+	}
+
+	p := &Program{
+		log:          c.Log,
+		script:       c.Script,
+		stateFile:    c.StateFile,
+		saveInterval: c.StateSaveInterval,
+		lastSave:     time.Now(),
+		modules:      newModules(c.Log),
+		loaded:       make(map[string]*loadEntry),
+		state:        starlark.NewDict(0),
+	}
+
+	if p.stateFile != "" {
+		if err := readState(p.stateFile, p.state); err != nil {
+			return nil, fmt.Errorf("reading state file: %v", err)
+		}
+	}
+
+	builtins, err := constantsDict(c.Constants)
+	if err != nil {
+		return nil, err
+	}
+	builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
+	builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
+	builtins["state"] = &stateDict{Dict: p.state}
+	p.builtins = builtins
+
+	name := c.Script
+	var src interface{}
+	if c.Source != "" {
+		name = c.Name
+		src = c.Source
+	}
+	_, program, err := starlark.SourceProgram(name, src, builtins.Has)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute source
+	p.thread = p.newThread(name)
+	p.globals, err = program.Init(p.thread, builtins)
+	if err != nil {
+		return nil, err
+	}
+
+	// Freeze the global state.  This prevents modifications to the plugin
+	// state and prevents scripts from containing errors storing tracking
+	// metrics.
+	p.globals.Freeze()
+	return p, nil
+}
+
+// Function returns the global function with the given name, it must take
+// exactly nparams parameters.
+func (p *Program) Function(name string, nparams int) (*starlark.Function, error) {
+	v := p.globals[name]
+	if v == nil {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	fn, ok := v.(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+
+	if fn.NumParams() != nparams {
+		return nil, fmt.Errorf("%s function must take %d parameter(s)", name, nparams)
+	}
+	return fn, nil
+}
+
+// Call calls the function, the backtrace of evaluation errors is logged.
+func (p *Program) Call(fn *starlark.Function, args starlark.Tuple) (starlark.Value, error) {
+	rv, err := starlark.Call(p.thread, fn, args, nil)
+	if err != nil {
+		if err, ok := err.(*starlark.EvalError); ok {
+			for _, line := range strings.Split(err.Backtrace(), "\n") {
+				p.log.Error(line)
+			}
+		}
+		return nil, err
+	}
+
+	if p.stateFile != "" && time.Since(p.lastSave) >= p.saveInterval {
+		if err := writeState(p.stateFile, p.state, p.log); err != nil {
+			p.log.Errorf("Writing state file failed: %v", err)
+		}
+		p.lastSave = time.Now()
+	}
+	return rv, nil
+}
+
+// State returns the dict shared by all calls.
+func (p *Program) State() *starlark.Dict {
+	return p.state
+}
+
+// Close saves the state if a state file is configured.
+func (p *Program) Close() error {
+	if p.stateFile != "" {
+		if err := writeState(p.stateFile, p.state, p.log); err != nil {
+			return fmt.Errorf("writing state file: %v", err)
+		}
+	}
+	return nil
+}
+
+func (p *Program) newThread(name string) *starlark.Thread {
+	return &starlark.Thread{
+		Name:  name,
+		Print: func(_ *starlark.Thread, msg string) { p.log.Debug(msg) },
+		Load:  p.load,
+	}
+}
+
+func init() {
+	// https://github.com/bazelbuild/starlark/issues/20
+	resolve.AllowNestedDef = true
+	resolve.AllowLambda = true
+	resolve.AllowFloat = true
+	resolve.AllowSet = true
+	resolve.AllowGlobalReassign = true
+	resolve.AllowRecursion = true
+}
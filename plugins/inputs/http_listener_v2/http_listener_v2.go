@@ -4,6 +4,8 @@ import (
 	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -17,6 +19,7 @@ import (
 	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
 )
 
 // defaultMaxBodySize is the default maximum request body size, in bytes.
@@ -209,9 +212,25 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	addMetric := func(m telegraf.Metric) error {
+		for headerName, measurementName := range h.HTTPHeaderTags {
+			headerValues, foundHeader := req.Header[headerName]
+			if foundHeader && len(headerValues) > 0 {
+				m.AddTag(measurementName, headerValues[0])
+			}
+		}
+
+		h.acc.AddMetric(m)
+		return nil
+	}
+
+	if rp, ok := h.Parser.(parsers.ReaderParser); ok && strings.ToLower(h.DataSource) != query {
+		h.streamBody(rp, addMetric, res, req)
+		return
+	}
+
 	var bytes []byte
 	var ok bool
-
 	switch strings.ToLower(h.DataSource) {
 	case query:
 		bytes, ok = h.collectQuery(res, req)
@@ -231,20 +250,86 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 	}
 
 	for _, m := range metrics {
-		for headerName, measurementName := range h.HTTPHeaderTags {
-			headerValues, foundHeader := req.Header[headerName]
-			if foundHeader && len(headerValues) > 0 {
-				m.AddTag(measurementName, headerValues[0])
-			}
-		}
-
-		h.acc.AddMetric(m)
+		addMetric(m)
 	}
 
 	res.WriteHeader(http.StatusNoContent)
 }
 
 func (h *HTTPListenerV2) collectBody(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	body, ok := h.openBody(res, req)
+	if !ok {
+		return nil, false
+	}
+	defer body.Close()
+
+	bytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		body.readError(res, err)
+		return nil, false
+	}
+
+	return bytes, true
+}
+
+// streamBody parses the request body while it is being read, adding the
+// metrics as they are parsed so the memory used does not grow with the size
+// of the body.  If only some of the metrics could be parsed the others are
+// still added and the request is answered as a partial write.
+func (h *HTTPListenerV2) streamBody(rp parsers.ReaderParser, addMetric func(telegraf.Metric) error, res http.ResponseWriter, req *http.Request) {
+	body, ok := h.openBody(res, req)
+	if !ok {
+		return
+	}
+	defer body.Close()
+
+	err := rp.ParseReader(body, addMetric)
+	if _, ok := err.(*influx.PartialParseError); ok {
+		h.Log.Debugf("Parse error: %s", err.Error())
+		partialWrite(res, err.Error())
+		return
+	}
+	if err != nil {
+		body.readError(res, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// requestBody is the request body limited to max_body_size.
+type requestBody struct {
+	io.ReadCloser
+	counter *countingReader
+	limit   int64
+	log     telegraf.Logger
+}
+
+// readError answers the request after reading the body failed, with 413 if
+// the body is larger than max_body_size and 400 otherwise, such as for a
+// corrupt gzip stream.
+func (b *requestBody) readError(res http.ResponseWriter, err error) {
+	if b.counter.n > b.limit {
+		tooLarge(res)
+		return
+	}
+	b.log.Debugf("Error reading the request body: %s", err.Error())
+	badRequest(res)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (h *HTTPListenerV2) openBody(res http.ResponseWriter, req *http.Request) (*requestBody, bool) {
 	body := req.Body
 
 	// Handle gzip request bodies
@@ -256,17 +341,17 @@ func (h *HTTPListenerV2) collectBody(res http.ResponseWriter, req *http.Request)
 			badRequest(res)
 			return nil, false
 		}
-		defer body.Close()
 	}
 
-	body = http.MaxBytesReader(res, body, h.MaxBodySize.Size)
-	bytes, err := ioutil.ReadAll(body)
-	if err != nil {
-		tooLarge(res)
-		return nil, false
-	}
-
-	return bytes, true
+	// The bytes are counted before the limit, which reads one byte more
+	// than allowed if the body is too large.
+	counter := &countingReader{ReadCloser: body}
+	return &requestBody{
+		ReadCloser: http.MaxBytesReader(res, counter, h.MaxBodySize.Size),
+		counter:    counter,
+		limit:      h.MaxBodySize.Size,
+		log:        h.Log,
+	}, true
 }
 
 func (h *HTTPListenerV2) collectQuery(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
@@ -305,6 +390,12 @@ func badRequest(res http.ResponseWriter) {
 	res.Write([]byte(`{"error":"http: bad request"}`))
 }
 
+func partialWrite(res http.ResponseWriter, errString string) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusBadRequest)
+	res.Write([]byte(fmt.Sprintf(`{"error":%q}`, "partial write: "+errString)))
+}
+
 func (h *HTTPListenerV2) authenticateIfSet(handler http.HandlerFunc, res http.ResponseWriter, req *http.Request) {
 	if h.BasicUsername != "" && h.BasicPassword != "" {
 		reqUsername, reqPassword, ok := req.BasicAuth()
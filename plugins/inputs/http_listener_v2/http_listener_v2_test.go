@@ -2,6 +2,7 @@ package http_listener_v2
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -32,6 +34,11 @@ cpu_load_short,host=server06 value=12.0 1422568543702900257
 `
 	badMsg = "blahblahblah: 42\n"
 
+	partialMsg = `cpu_load_short,host=server01 value=12.0 1422568543702900257
+cpu_load_short,host=server02 value= 1422568543702900257
+cpu_load_short,host=server03 value=12.0 1422568543702900257
+`
+
 	emptyMsg = ""
 
 	basicUsername = "test-username-please-ignore"
@@ -275,6 +282,46 @@ func TestWriteHTTPVerySmallMaxBody(t *testing.T) {
 	require.EqualValues(t, 413, resp.StatusCode)
 }
 
+func TestWriteHTTPStreamErrors(t *testing.T) {
+	parser, _ := parsers.NewInfluxParser()
+
+	listener := &HTTPListenerV2{
+		Log:            testutil.Logger{},
+		ServiceAddress: "localhost:0",
+		Path:           "/write",
+		Methods:        []string{"POST"},
+		Parser:         parser,
+		MaxBodySize:    internal.Size{Size: 4096},
+		TimeFunc:       time.Now,
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// Without a content length the size is only known while reading.
+	resp, err := http.Post(createURL(listener, "http", "/write", ""), "", ioutil.NopCloser(strings.NewReader(hugeMetric)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 413, resp.StatusCode)
+
+	// A corrupt body is a bad request.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write([]byte(testMsgs))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	data := buf.Bytes()[:buf.Len()/2]
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), ioutil.NopCloser(bytes.NewReader(data)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+}
+
 // test that writing gzipped data works
 func TestWriteHTTPGzippedData(t *testing.T) {
 	listener := newTestHTTPListenerV2()
@@ -367,6 +414,31 @@ func TestWriteHTTPInvalid(t *testing.T) {
 	require.EqualValues(t, 400, resp.StatusCode)
 }
 
+func TestWriteHTTPPartial(t *testing.T) {
+	listener := newTestHTTPListenerV2()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Post(createURL(listener, "http", "/write", "db=mydb"), "", bytes.NewBuffer([]byte(partialMsg)))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+	require.Contains(t, string(body), "partial write: metric parse error")
+
+	acc.Wait(2)
+	for _, hostTag := range []string{"server01", "server03"} {
+		acc.AssertContainsTaggedFields(t, "cpu_load_short",
+			map[string]interface{}{"value": float64(12)},
+			map[string]string{"host": hostTag},
+		)
+	}
+	require.Len(t, acc.Metrics, 2)
+}
+
 func TestWriteHTTPEmpty(t *testing.T) {
 	listener := newTestHTTPListenerV2()
 
@@ -285,37 +285,19 @@ func (h *InfluxDBListener) handleWrite() http.HandlerFunc {
 			parser.SetTimePrecision(precision)
 		}
 
-		var m telegraf.Metric
-		var err error
-		var parseErrorCount int
+		// Continue parsing metrics even if some are malformed, they are
+		// reported as a partial write once the body has been read.
 		var lastPos int = 0
-		var firstParseErrorStr string
-		for {
-			select {
-			case <-req.Context().Done():
-				// Shutting down before parsing is finished.
-				res.WriteHeader(http.StatusServiceUnavailable)
-				return
-			default:
-			}
-
-			m, err = parser.Next()
+		err := parser.ParseAll(func(m telegraf.Metric) error {
 			pos := parser.Position()
 			h.bytesRecv.Incr(int64(pos - lastPos))
 			lastPos = pos
 
-			// Continue parsing metrics even if some are malformed
-			if parseErr, ok := err.(*influx.ParseError); ok {
-				parseErrorCount += 1
-				errStr := parseErr.Error()
-				if firstParseErrorStr == "" {
-					firstParseErrorStr = errStr
-				}
-				continue
-			} else if err != nil {
-				// Either we're exiting cleanly (err ==
-				// influx.EOF) or there's an unexpected error
-				break
+			select {
+			case <-req.Context().Done():
+				// Shutting down before parsing is finished.
+				return req.Context().Err()
+			default:
 			}
 
 			if h.DatabaseTag != "" && db != "" {
@@ -327,24 +309,21 @@ func (h *InfluxDBListener) handleWrite() http.HandlerFunc {
 			}
 
 			h.acc.AddMetric(m)
+			return nil
+		})
+		h.bytesRecv.Incr(int64(parser.Position() - lastPos))
 
+		if partialErr, ok := err.(*influx.PartialParseError); ok {
+			partialWrite(res, partialErr.Error())
+			return
 		}
-		if err != influx.EOF {
-			h.Log.Debugf("Error parsing the request body: %v", err.Error())
-			badRequest(res, err.Error())
+		if err != nil && err == req.Context().Err() {
+			res.WriteHeader(http.StatusServiceUnavailable)
 			return
 		}
-		if parseErrorCount > 0 {
-			var partialErrorString string
-			switch parseErrorCount {
-			case 1:
-				partialErrorString = fmt.Sprintf("%s", firstParseErrorStr)
-			case 2:
-				partialErrorString = fmt.Sprintf("%s (and 1 other parse error)", firstParseErrorStr)
-			default:
-				partialErrorString = fmt.Sprintf("%s (and %d other parse errors)", firstParseErrorStr, parseErrorCount-1)
-			}
-			partialWrite(res, partialErrorString)
+		if err != nil {
+			h.Log.Debugf("Error parsing the request body: %v", err.Error())
+			badRequest(res, err.Error())
 			return
 		}
 
@@ -1,6 +1,7 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -23,6 +24,49 @@ type File struct {
 	writer     io.Writer
 	closers    []io.Closer
 	serializer serializers.Serializer
+	// headers write the files for serializers with a header.
+	headers []*headerWriter
+}
+
+// headerWriter writes rows to a file, preceded by the header at the start of
+// the file and whenever the header changes.
+type headerWriter struct {
+	io.Writer
+
+	// size returns the size of the file, nil if it can not be determined.
+	size func() int64
+	// written is set once anything is written.
+	written bool
+	// last is the header of the last rows written, nil if unknown.
+	last []byte
+}
+
+func (w *headerWriter) write(header, rows []byte) error {
+	if len(header) > 0 {
+		if w.empty() {
+			w.last = nil
+		} else if w.last == nil {
+			// Appending to an existing file, assume it starts with the same
+			// header.
+			w.last = header
+		}
+		if !bytes.Equal(header, w.last) {
+			if _, err := w.Write(header); err != nil {
+				return err
+			}
+			w.last = header
+		}
+	}
+	w.written = true
+	_, err := w.Write(rows)
+	return err
+}
+
+func (w *headerWriter) empty() bool {
+	if w.size == nil {
+		return !w.written
+	}
+	return w.size() == 0
 }
 
 var sampleConfig = `
@@ -59,6 +103,7 @@ func (f *File) SetSerializer(serializer serializers.Serializer) {
 
 func (f *File) Connect() error {
 	writers := []io.Writer{}
+	f.headers = nil
 
 	if len(f.Files) == 0 {
 		f.Files = []string{"stdout"}
@@ -67,6 +112,7 @@ func (f *File) Connect() error {
 	for _, file := range f.Files {
 		if file == "stdout" {
 			writers = append(writers, os.Stdout)
+			f.headers = append(f.headers, &headerWriter{Writer: os.Stdout})
 		} else {
 			of, err := rotate.NewFileWriter(
 				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
@@ -76,12 +122,31 @@ func (f *File) Connect() error {
 
 			writers = append(writers, of)
 			f.closers = append(f.closers, of)
+			f.headers = append(f.headers, &headerWriter{Writer: of, size: fileSize(of)})
 		}
 	}
 	f.writer = io.MultiWriter(writers...)
 	return nil
 }
 
+// fileSize returns a function returning the size of the file.
+func fileSize(w io.Writer) func() int64 {
+	switch w := w.(type) {
+	case *rotate.FileWriter:
+		return w.Size
+	case *os.File:
+		return func() int64 {
+			info, err := w.Stat()
+			if err != nil {
+				return 0
+			}
+			return info.Size()
+		}
+	default:
+		return nil
+	}
+}
+
 func (f *File) Close() error {
 	var err error
 	for _, c := range f.closers {
@@ -102,6 +167,10 @@ func (f *File) Description() string {
 }
 
 func (f *File) Write(metrics []telegraf.Metric) error {
+	if s, ok := f.serializer.(serializers.HeaderSerializer); ok {
+		return f.writeWithHeader(s, metrics)
+	}
+
 	var writeErr error = nil
 
 	if f.UseBatchFormat {
@@ -131,6 +200,33 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return writeErr
 }
 
+// writeWithHeader writes the metrics to each file, with the header at the
+// start of the file and whenever the columns change.
+func (f *File) writeWithHeader(s serializers.HeaderSerializer, metrics []telegraf.Metric) error {
+	batches := [][]telegraf.Metric{metrics}
+	if !f.UseBatchFormat {
+		batches = make([][]telegraf.Metric, 0, len(metrics))
+		for _, metric := range metrics {
+			batches = append(batches, []telegraf.Metric{metric})
+		}
+	}
+
+	var writeErr error
+	for _, batch := range batches {
+		header, rows, err := s.SerializeRows(batch)
+		if err != nil {
+			f.Log.Errorf("Could not serialize metric: %v", err)
+			continue
+		}
+		for _, w := range f.headers {
+			if err := w.write(header, rows); err != nil {
+				writeErr = fmt.Errorf("E! [outputs.file] failed to write message: %v", err)
+			}
+		}
+	}
+	return writeErr
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
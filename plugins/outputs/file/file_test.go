@@ -6,9 +6,12 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
@@ -180,6 +183,42 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileCSVHeader(t *testing.T) {
+	fh1 := createFile()
+	defer os.Remove(fh1.Name())
+	fh2 := tmpFile()
+	defer os.Remove(fh2)
+
+	s, err := serializers.NewCSVSerializer(&serializers.Config{CSVHeader: true})
+	require.NoError(t, err)
+	f := File{
+		Files:      []string{fh1.Name(), fh2},
+		serializer: s,
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, f.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 2}, time.Unix(10, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"used": 3}, time.Unix(10, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+	require.NoError(t, f.Close())
+
+	// The existing file is assumed to start with the header.
+	validateFile(fh1.Name(), "cpu,cpu=cpu0 value=100 1455312810012459582\n"+
+		"0,cpu,1\n"+
+		"10,cpu,2\n"+
+		"timestamp,measurement,used\n"+
+		"10,mem,3\n", t)
+	validateFile(fh2, "timestamp,measurement,usage\n"+
+		"0,cpu,1\n"+
+		"10,cpu,2\n"+
+		"timestamp,measurement,used\n"+
+		"10,mem,3\n", t)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -0,0 +1,323 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/jackc/pgx"
+)
+
+var sampleConfig = `
+  ## Connection string, either a URL or key/value pairs.
+  ## See https://godoc.org/github.com/jackc/pgx#ParseConnectionString
+  connection = "host=localhost user=postgres sslmode=verify-full"
+
+  ## Schema of the tables.
+  # schema = "public"
+
+  ## Column to store the metric time in.
+  # timestamp_column = "time"
+
+  ## Store the tags in a table per measurement, named by the measurement
+  ## with the tag_table_suffix, referenced from the metric table by the
+  ## "tag_id" column.
+  # tags_as_foreign_keys = false
+  # tag_table_suffix = "_tag"
+
+  ## Store all tags in a single "tags" JSONB column instead of a column per
+  ## tag.
+  # tags_as_jsonb = false
+
+  ## Store all fields in a single "fields" JSONB column instead of a column
+  ## per field.
+  # fields_as_jsonb = false
+
+  ## Statements run to create a table.  {TABLE} is replaced by the quoted
+  ## table name with the schema, {TABLELITERAL} by the same name as a string
+  ## literal and {COLUMNS} by the column definitions.
+  ##
+  ## To create a TimescaleDB hypertable:
+  ##   create_templates = [
+  ##     "CREATE TABLE {TABLE} ({COLUMNS})",
+  ##     "SELECT create_hypertable({TABLELITERAL}, 'time', chunk_time_interval => INTERVAL '1d')",
+  ##   ]
+  # create_templates = ["CREATE TABLE {TABLE} ({COLUMNS})"]
+
+  ## Statements run to create a tag table.
+  # tag_table_create_templates = ["CREATE TABLE {TABLE} ({COLUMNS})"]
+
+  ## Statements run to add a column for a new tag or field.  {COLUMN} is
+  ## replaced by the column definition.
+  # add_column_templates = ["ALTER TABLE {TABLE} ADD COLUMN IF NOT EXISTS {COLUMN}"]
+`
+
+type Postgresql struct {
+	Connection              string   `toml:"connection"`
+	Schema                  string   `toml:"schema"`
+	TimestampColumn         string   `toml:"timestamp_column"`
+	TagsAsForeignKeys       bool     `toml:"tags_as_foreign_keys"`
+	TagTableSuffix          string   `toml:"tag_table_suffix"`
+	TagsAsJSONB             bool     `toml:"tags_as_jsonb"`
+	FieldsAsJSONB           bool     `toml:"fields_as_jsonb"`
+	CreateTemplates         []string `toml:"create_templates"`
+	TagTableCreateTemplates []string `toml:"tag_table_create_templates"`
+	AddColumnTemplates      []string `toml:"add_column_templates"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	db *pgx.ConnPool
+	// tables are the types of the columns of the known tables.
+	tables map[string]map[string]string
+	// tagIDs are the tag ids stored in each tag table.
+	tagIDs map[string]map[int64]bool
+	// warned are the names and columns already warned about.
+	warned map[string]bool
+}
+
+func (p *Postgresql) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Postgresql) Description() string {
+	return "Send metrics to PostgreSQL or TimescaleDB, with a table per measurement"
+}
+
+func (p *Postgresql) Connect() error {
+	config, err := pgx.ParseConnectionString(p.Connection)
+	if err != nil {
+		return err
+	}
+	p.db, err = pgx.NewConnPool(pgx.ConnPoolConfig{ConnConfig: config})
+	if err != nil {
+		return err
+	}
+	p.reset()
+	return nil
+}
+
+func (p *Postgresql) Close() error {
+	if p.db != nil {
+		p.db.Close()
+	}
+	return nil
+}
+
+// reset forgets the known tables and tag ids, as they may have been changed
+// outside of Telegraf or by a failed write.
+func (p *Postgresql) reset() {
+	p.tables = make(map[string]map[string]string)
+	p.tagIDs = make(map[string]map[int64]bool)
+	p.warned = make(map[string]bool)
+}
+
+func (p *Postgresql) Write(metrics []telegraf.Metric) error {
+	names := make([]string, 0)
+	byName := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		if _, ok := byName[m.Name()]; !ok {
+			if !p.validIdentifier(m.Name()) || (p.TagsAsForeignKeys && !p.validIdentifier(m.Name()+p.TagTableSuffix)) {
+				continue
+			}
+			names = append(names, m.Name())
+		}
+		byName[m.Name()] = append(byName[m.Name()], m)
+	}
+
+	batches := make([]*batch, 0, len(names))
+	for _, name := range names {
+		b := p.newBatch(name, byName[name])
+		if b.tagTable != nil {
+			if err := p.ensureTable(b.tagTable, p.TagTableCreateTemplates); err != nil {
+				p.reset()
+				return err
+			}
+		}
+		if err := p.ensureTable(b.table, p.CreateTemplates); err != nil {
+			p.reset()
+			return err
+		}
+		batches = append(batches, b)
+	}
+
+	if err := p.copy(batches); err != nil {
+		p.reset()
+		return err
+	}
+
+	for _, b := range batches {
+		if b.tagTable == nil {
+			continue
+		}
+		ids, ok := p.tagIDs[b.tagTable.name]
+		if !ok {
+			ids = make(map[int64]bool)
+			p.tagIDs[b.tagTable.name] = ids
+		}
+		for _, id := range b.tagIDs {
+			ids[id] = true
+		}
+	}
+	return nil
+}
+
+// copy writes the batches in a single transaction, inserting the new tag
+// rows before copying the metric rows.
+func (p *Postgresql) copy(batches []*batch) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, b := range batches {
+		if b.tagTable != nil && len(b.tagRows) > 0 {
+			query := p.insertTagsSQL(b.tagTable)
+			for _, values := range p.values(b.tagTable, b.tagRows) {
+				if _, err := tx.Exec(query, values...); err != nil {
+					return fmt.Errorf("inserting tags into %q failed: %v", b.tagTable.name, err)
+				}
+			}
+		}
+
+		rows := p.values(b.table, b.rows)
+		if _, err := tx.CopyFrom(pgx.Identifier{p.Schema, b.table.name}, b.table.columns, pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("copying into %q failed: %v", b.table.name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ensureTable creates the table or adds its columns missing in the
+// database.
+func (p *Postgresql) ensureTable(t *table, createTemplates []string) error {
+	known, ok := p.tables[t.name]
+	if !ok {
+		var err error
+		known, err = p.existingColumns(t.name)
+		if err != nil {
+			return err
+		}
+		if len(known) == 0 {
+			defs := make([]string, 0, len(t.columns))
+			for _, c := range t.columns {
+				defs = append(defs, p.columnDefinition(t, c))
+			}
+			if err := p.exec(createTemplates, t, strings.Join(defs, ", ")); err != nil {
+				return fmt.Errorf("creating table %q failed: %v", t.name, err)
+			}
+			for _, c := range t.columns {
+				known[c] = t.types[c]
+			}
+		}
+		p.tables[t.name] = known
+	}
+
+	for _, c := range t.columns {
+		if have, ok := known[c]; ok {
+			typ, ok := widen(have, t.types[c])
+			if !ok {
+				continue
+			}
+			query := "ALTER TABLE " + p.fullName(t.name) + " ALTER COLUMN " + quoteIdent(c) + " TYPE " + typ
+			if _, err := p.db.Exec(query); err != nil {
+				return fmt.Errorf("changing type of column %q of table %q failed: %v", c, t.name, err)
+			}
+			known[c] = typ
+			continue
+		}
+		if err := p.exec(p.AddColumnTemplates, t, p.columnDefinition(t, c)); err != nil {
+			return fmt.Errorf("adding column %q to table %q failed: %v", c, t.name, err)
+		}
+		known[c] = t.types[c]
+	}
+	return nil
+}
+
+// existingColumns returns the types of the columns of the table, which are
+// empty if the table does not exist.
+func (p *Postgresql) existingColumns(name string) (map[string]string, error) {
+	rows, err := p.db.Query(
+		"SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2",
+		p.Schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var column, typ string
+		if err := rows.Scan(&column, &typ); err != nil {
+			return nil, err
+		}
+		columns[column] = typ
+	}
+	return columns, rows.Err()
+}
+
+func (p *Postgresql) exec(templates []string, t *table, columns string) error {
+	for _, template := range templates {
+		if _, err := p.db.Exec(p.render(template, t, columns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render replaces the placeholders of the template, columns replaces both
+// {COLUMNS} and {COLUMN}.
+func (p *Postgresql) render(template string, t *table, columns string) string {
+	name := p.fullName(t.name)
+	return strings.NewReplacer(
+		"{TABLE}", name,
+		"{TABLELITERAL}", quoteLiteral(name),
+		"{COLUMNS}", columns,
+		"{COLUMN}", columns,
+	).Replace(template)
+}
+
+func (p *Postgresql) columnDefinition(t *table, column string) string {
+	def := quoteIdent(column) + " " + t.types[column]
+	if constraint, ok := t.constraints[column]; ok {
+		def += " " + constraint
+	}
+	return def
+}
+
+func (p *Postgresql) insertTagsSQL(t *table) string {
+	columns := make([]string, 0, len(t.columns))
+	placeholders := make([]string, 0, len(t.columns))
+	for i, c := range t.columns {
+		columns = append(columns, quoteIdent(c))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+	return "INSERT INTO " + p.fullName(t.name) + " (" + strings.Join(columns, ", ") +
+		") VALUES (" + strings.Join(placeholders, ", ") + ") ON CONFLICT (" + quoteIdent(tagIDColumn) + ") DO NOTHING"
+}
+
+func (p *Postgresql) fullName(name string) string {
+	return pgx.Identifier{p.Schema, name}.Sanitize()
+}
+
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func init() {
+	outputs.Add("postgresql", func() telegraf.Output {
+		return &Postgresql{
+			Schema:                  "public",
+			TimestampColumn:         "time",
+			TagTableSuffix:          "_tag",
+			CreateTemplates:         []string{"CREATE TABLE {TABLE} ({COLUMNS})"},
+			TagTableCreateTemplates: []string{"CREATE TABLE {TABLE} ({COLUMNS})"},
+			AddColumnTemplates:      []string{"ALTER TABLE {TABLE} ADD COLUMN IF NOT EXISTS {COLUMN}"},
+		}
+	})
+}
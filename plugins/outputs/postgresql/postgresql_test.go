@@ -0,0 +1,292 @@
+package postgresql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/require"
+)
+
+func newPostgresql() *Postgresql {
+	p := outputs.Outputs["postgresql"]().(*Postgresql)
+	p.Log = testutil.Logger{}
+	p.reset()
+	return p
+}
+
+var testMetrics = []telegraf.Metric{
+	testutil.MustMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 42.5, "count": int64(3)},
+		time.Unix(0, 0),
+	),
+	testutil.MustMetric("cpu",
+		map[string]string{"host": "b", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 12.0, "ok": true},
+		time.Unix(10, 0),
+	),
+	testutil.MustMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 13.0},
+		time.Unix(20, 0),
+	),
+}
+
+func TestBatchInline(t *testing.T) {
+	p := newPostgresql()
+	b := p.newBatch("cpu", testMetrics)
+
+	require.Nil(t, b.tagTable)
+	require.Equal(t, []string{"time", "cpu", "host", "count", "usage", "ok"}, b.table.columns)
+	require.Equal(t, map[string]string{
+		"time":  typeTimestamp,
+		"cpu":   typeText,
+		"host":  typeText,
+		"count": typeBigint,
+		"usage": typeDouble,
+		"ok":    typeBoolean,
+	}, b.table.types)
+
+	p.tables["cpu"] = b.table.types
+	require.Equal(t, [][]interface{}{
+		{time.Unix(0, 0).UTC(), "cpu0", "a", int64(3), 42.5, nil},
+		{time.Unix(10, 0).UTC(), "cpu0", "b", nil, 12.0, true},
+		{time.Unix(20, 0).UTC(), "cpu0", "a", nil, 13.0, nil},
+	}, p.values(b.table, b.rows))
+}
+
+func TestBatchForeignKeys(t *testing.T) {
+	p := newPostgresql()
+	p.TagsAsForeignKeys = true
+	p.FieldsAsJSONB = true
+	b := p.newBatch("cpu", testMetrics)
+
+	require.Equal(t, "cpu_tag", b.tagTable.name)
+	require.Equal(t, []string{"tag_id", "cpu", "host"}, b.tagTable.columns)
+	require.Equal(t, []string{"time", "tag_id", "fields"}, b.table.columns)
+	require.Equal(t, `"tag_id" bigint REFERENCES "public"."cpu_tag" ("tag_id")`, p.columnDefinition(b.table, "tag_id"))
+	require.Equal(t, `"tag_id" bigint PRIMARY KEY`, p.columnDefinition(b.tagTable, "tag_id"))
+
+	// Each tag set is inserted once.
+	idA := tagID(testMetrics[0])
+	idB := tagID(testMetrics[1])
+	require.Equal(t, idA, tagID(testMetrics[2]))
+	require.NotEqual(t, idA, idB)
+	require.Equal(t, []int64{idA, idB}, b.tagIDs)
+
+	p.tables["cpu_tag"] = b.tagTable.types
+	require.Equal(t, [][]interface{}{
+		{idA, "cpu0", "a"},
+		{idB, "cpu0", "b"},
+	}, p.values(b.tagTable, b.tagRows))
+
+	p.tables["cpu"] = b.table.types
+	rows := p.values(b.table, b.rows)
+	require.Equal(t, []interface{}{time.Unix(0, 0).UTC(), idA, map[string]interface{}{"usage": 42.5, "count": int64(3)}}, rows[0])
+	require.Equal(t, idA, rows[2][1])
+
+	// Known tag sets are not inserted again.
+	p.tagIDs["cpu_tag"] = map[int64]bool{idA: true}
+	b = p.newBatch("cpu", testMetrics)
+	require.Equal(t, []int64{idB}, b.tagIDs)
+}
+
+func TestBatchTagsAsJSONB(t *testing.T) {
+	p := newPostgresql()
+	p.TagsAsJSONB = true
+	b := p.newBatch("cpu", testMetrics[:1])
+
+	require.Equal(t, []string{"time", "tags", "count", "usage"}, b.table.columns)
+	require.Equal(t, map[string]string{"host": "a", "cpu": "cpu0"}, b.rows[0]["tags"])
+}
+
+func TestValuesExistingTypes(t *testing.T) {
+	p := newPostgresql()
+	b := p.newBatch("disk", []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{},
+			map[string]interface{}{"used": int64(5), "free": uint64(math.MaxUint64), "total": uint64(10), "state": "ok"},
+			time.Unix(0, 0),
+		),
+	})
+	p.tables["disk"] = map[string]string{
+		"time":  typeTimestamp,
+		"used":  typeDouble,
+		"free":  typeBigint,
+		"total": typeBigint,
+		"state": typeBoolean,
+	}
+
+	require.Equal(t, []string{"time", "free", "state", "total", "used"}, b.table.columns)
+	require.Equal(t, [][]interface{}{
+		{time.Unix(0, 0).UTC(), nil, nil, int64(10), 5.0},
+	}, p.values(b.table, b.rows))
+}
+
+func TestBatchWidensTypes(t *testing.T) {
+	p := newPostgresql()
+	b := p.newBatch("disk", []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{},
+			map[string]interface{}{"used": int64(5), "free": int64(1), "state": "ok"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("disk",
+			map[string]string{},
+			map[string]interface{}{"used": 5.5, "free": uint64(math.MaxUint64), "state": true},
+			time.Unix(10, 0),
+		),
+	})
+	require.Equal(t, typeDouble, b.table.types["used"])
+	require.Equal(t, typeNumeric, b.table.types["free"])
+	require.Equal(t, typeText, b.table.types["state"])
+}
+
+func TestLongNames(t *testing.T) {
+	p := newPostgresql()
+	long := strings.Repeat("x", maxIdentifierLength+1)
+	b := p.newBatch("cpu", []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", long: "b"},
+			map[string]interface{}{"usage": 1.0, long: 2.0},
+			time.Unix(0, 0),
+		),
+	})
+	require.Equal(t, []string{"time", "host", "usage"}, b.table.columns)
+	require.True(t, p.validIdentifier(strings.Repeat("x", maxIdentifierLength)))
+	require.False(t, p.validIdentifier(long))
+}
+
+func TestReservedNames(t *testing.T) {
+	p := newPostgresql()
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "a", "time": "b", "tag_id": "c"},
+		map[string]interface{}{"usage": 1.0, "time": 2.0, "tag_id": int64(3)},
+		time.Unix(0, 0),
+	)
+
+	b := p.newBatch("cpu", []telegraf.Metric{m})
+	require.Equal(t, []string{"time", "host", "tag_id", "usage"}, b.table.columns)
+	require.Equal(t, time.Unix(0, 0).UTC(), b.rows[0]["time"])
+	require.Equal(t, int64(3), b.rows[0]["tag_id"])
+
+	p.TagsAsForeignKeys = true
+	b = p.newBatch("cpu", []telegraf.Metric{m})
+	require.Equal(t, []string{"time", "tag_id", "usage"}, b.table.columns)
+	require.Equal(t, []string{"tag_id", "host", "time"}, b.tagTable.columns)
+	require.Equal(t, tagID(m), b.rows[0]["tag_id"])
+	require.Equal(t, tagID(m), b.tagRows[0]["tag_id"])
+}
+
+func TestRender(t *testing.T) {
+	p := newPostgresql()
+	p.Schema = "metrics"
+	table := newTable("it's")
+	require.Equal(t,
+		`SELECT create_hypertable('"metrics"."it''s"', 'time')`,
+		p.render("SELECT create_hypertable({TABLELITERAL}, 'time')", table, ""))
+	require.Equal(t,
+		`CREATE TABLE "metrics"."it's" ("time" timestamp with time zone)`,
+		p.render(p.CreateTemplates[0], table, `"time" timestamp with time zone`))
+
+	table.add("tag_id", typeBigint)
+	table.add("host", typeText)
+	require.Equal(t,
+		`INSERT INTO "metrics"."it's" ("tag_id", "host") VALUES ($1, $2) ON CONFLICT ("tag_id") DO NOTHING`,
+		p.insertTagsSQL(table))
+}
+
+func newIntegration(t *testing.T) *Postgresql {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	p := newPostgresql()
+	p.Connection = fmt.Sprintf("host=%s user=postgres sslmode=disable", testutil.GetLocalHost())
+	require.NoError(t, p.Connect())
+
+	for _, table := range []string{"cpu", "cpu_tag"} {
+		_, err := p.db.Exec("DROP TABLE IF EXISTS " + p.fullName(table))
+		require.NoError(t, err)
+	}
+	return p
+}
+
+func queryRows(t *testing.T, db *pgx.ConnPool, query string) [][]interface{} {
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var result [][]interface{}
+	for rows.Next() {
+		values, err := rows.Values()
+		require.NoError(t, err)
+		result = append(result, values)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestWriteIntegration(t *testing.T) {
+	p := newIntegration(t)
+	defer p.Close()
+
+	require.NoError(t, p.Write(testMetrics))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "c", "cpu": "cpu1", "rack": "r1"},
+			map[string]interface{}{"usage": 1.0, "state": "up"},
+			time.Unix(30, 0),
+		),
+	}))
+
+	rows := queryRows(t, p.db, `SELECT host, rack, count, usage, ok, state FROM cpu ORDER BY time`)
+	require.Equal(t, [][]interface{}{
+		{"a", nil, int64(3), 42.5, nil, nil},
+		{"b", nil, nil, 12.0, true, nil},
+		{"a", nil, nil, 13.0, nil, nil},
+		{"c", "r1", nil, 1.0, nil, "up"},
+	}, rows)
+}
+
+func TestWriteForeignKeysIntegration(t *testing.T) {
+	p := newIntegration(t)
+	defer p.Close()
+	p.TagsAsForeignKeys = true
+
+	require.NoError(t, p.Write(testMetrics))
+	require.NoError(t, p.Write(testMetrics))
+
+	rows := queryRows(t, p.db, `SELECT t.host, count(*) FROM cpu c JOIN cpu_tag t USING (tag_id) GROUP BY t.host ORDER BY t.host`)
+	require.Equal(t, [][]interface{}{
+		{"a", int64(4)},
+		{"b", int64(2)},
+	}, rows)
+}
+
+func TestWriteWidensColumnsIntegration(t *testing.T) {
+	p := newIntegration(t)
+	defer p.Close()
+
+	require.NoError(t, p.Write(testMetrics))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "c", "cpu": "cpu1"},
+			map[string]interface{}{"count": 2.5},
+			time.Unix(30, 0),
+		),
+	}))
+
+	rows := queryRows(t, p.db, `SELECT host, count FROM cpu WHERE count IS NOT NULL ORDER BY time`)
+	require.Equal(t, [][]interface{}{
+		{"a", 3.0},
+		{"c", 2.5},
+	}, rows)
+}
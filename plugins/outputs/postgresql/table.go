@@ -0,0 +1,294 @@
+package postgresql
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	tagIDColumn      = "tag_id"
+	tagsJSONColumn   = "tags"
+	fieldsJSONColumn = "fields"
+
+	// maxIdentifierLength is the length in bytes PostgreSQL truncates
+	// longer table and column names to.
+	maxIdentifierLength = 63
+)
+
+// Column types, named as reported by information_schema.columns so they can
+// be compared with the types of existing columns.
+const (
+	typeTimestamp = "timestamp with time zone"
+	typeText      = "text"
+	typeBigint    = "bigint"
+	typeNumeric   = "numeric"
+	typeDouble    = "double precision"
+	typeBoolean   = "boolean"
+	typeJSONB     = "jsonb"
+)
+
+// table is the layout of a table needed by a batch of metrics.
+type table struct {
+	name    string
+	columns []string
+	types   map[string]string
+	// constraints are added to the column definitions when the table is
+	// created.
+	constraints map[string]string
+}
+
+func newTable(name string) *table {
+	return &table{
+		name:        name,
+		types:       make(map[string]string),
+		constraints: make(map[string]string),
+	}
+}
+
+// add adds a column unless the table already has one with the name, in
+// which case the type is widened to fit both types if possible.
+func (t *table) add(name, typ string) {
+	if have, ok := t.types[name]; ok {
+		if wider, ok := widen(have, typ); ok {
+			t.types[name] = wider
+		}
+		return
+	}
+	t.columns = append(t.columns, name)
+	t.types[name] = typ
+}
+
+// batch are the rows of a measurement to write.
+type batch struct {
+	table *table
+	rows  []map[string]interface{}
+
+	// The tag table and its new rows when tags are stored as foreign keys.
+	tagTable *table
+	tagRows  []map[string]interface{}
+	tagIDs   []int64
+}
+
+// newBatch returns the tables and rows of the metrics of a measurement.
+func (p *Postgresql) newBatch(name string, metrics []telegraf.Metric) *batch {
+	b := &batch{table: newTable(name)}
+	b.table.add(p.TimestampColumn, typeTimestamp)
+
+	tags := b.table
+	if p.TagsAsForeignKeys {
+		b.tagTable = newTable(name + p.TagTableSuffix)
+		b.tagTable.add(tagIDColumn, typeBigint)
+		b.tagTable.constraints[tagIDColumn] = "PRIMARY KEY"
+		tags = b.tagTable
+
+		b.table.add(tagIDColumn, typeBigint)
+		b.table.constraints[tagIDColumn] = "REFERENCES " + p.fullName(b.tagTable.name) +
+			" (" + quoteIdent(tagIDColumn) + ")"
+	}
+
+	// Tags and fields named like the columns added by the plugin would
+	// overwrite them.
+	reserved := map[string]bool{p.TimestampColumn: true}
+	if p.TagsAsForeignKeys {
+		reserved[tagIDColumn] = true
+	} else if p.TagsAsJSONB {
+		reserved[tagsJSONColumn] = true
+	}
+	if p.FieldsAsJSONB {
+		reserved[fieldsJSONColumn] = true
+	}
+	tagReserved := reserved
+	if p.TagsAsForeignKeys {
+		tagReserved = map[string]bool{tagIDColumn: true}
+	}
+
+	seen := make(map[int64]bool)
+	for _, m := range metrics {
+		row := map[string]interface{}{p.TimestampColumn: m.Time().UTC()}
+		fields := m.Fields()
+
+		tagRow := row
+		if p.TagsAsForeignKeys {
+			id := tagID(m)
+			row[tagIDColumn] = id
+			tagRow = nil
+			if !seen[id] && !p.tagIDs[b.tagTable.name][id] {
+				seen[id] = true
+				tagRow = map[string]interface{}{tagIDColumn: id}
+				b.tagRows = append(b.tagRows, tagRow)
+				b.tagIDs = append(b.tagIDs, id)
+			}
+		}
+		if tagRow != nil {
+			if p.TagsAsJSONB {
+				tags.add(tagsJSONColumn, typeJSONB)
+				tagRow[tagsJSONColumn] = m.Tags()
+			} else {
+				for _, tag := range m.TagList() {
+					// A field replaces a tag with the same key.
+					if _, ok := fields[tag.Key]; ok && !p.TagsAsForeignKeys {
+						continue
+					}
+					if !p.validColumn(tag.Key, tagReserved) {
+						continue
+					}
+					tags.add(tag.Key, typeText)
+					tagRow[tag.Key] = tag.Value
+				}
+			}
+		}
+
+		if p.FieldsAsJSONB {
+			b.table.add(fieldsJSONColumn, typeJSONB)
+			row[fieldsJSONColumn] = fields
+		} else {
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				if p.validColumn(key, reserved) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				b.table.add(key, columnType(fields[key]))
+				row[key] = fields[key]
+			}
+		}
+		b.rows = append(b.rows, row)
+	}
+	return b
+}
+
+// validColumn reports if a tag or field can be stored in a column with its
+// name, names of the columns added by the plugin are left out.
+func (p *Postgresql) validColumn(name string, reserved map[string]bool) bool {
+	if reserved[name] {
+		if key := "reserved." + name; !p.warned[key] {
+			p.warned[key] = true
+			p.Log.Warnf("Name %q is used by a column of the plugin, leaving it out", name)
+		}
+		return false
+	}
+	return p.validIdentifier(name)
+}
+
+// validIdentifier reports if the name can be used as a table or column name,
+// names PostgreSQL would truncate are left out as they could clash.
+func (p *Postgresql) validIdentifier(name string) bool {
+	if len(name) <= maxIdentifierLength {
+		return true
+	}
+	if !p.warned[name] {
+		p.warned[name] = true
+		p.Log.Warnf("Name %q is longer than %d bytes, leaving it out", name, maxIdentifierLength)
+	}
+	return false
+}
+
+// values returns the rows in the column order of the table, converted to
+// the types of the columns.  Values that do not fit are left out.
+func (p *Postgresql) values(t *table, rows []map[string]interface{}) [][]interface{} {
+	types := p.tables[t.name]
+	result := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values := make([]interface{}, len(t.columns))
+		for i, c := range t.columns {
+			v, ok := row[c]
+			if !ok {
+				continue
+			}
+			converted, ok := convert(types[c], v)
+			if !ok {
+				// Only warn once per column, all values of a type not fitting
+				// the column are left out.
+				if key := t.name + "." + c; !p.warned[key] {
+					p.warned[key] = true
+					p.Log.Warnf("Values of column %q in table %q do not fit its type %q, leaving them out",
+						c, t.name, types[c])
+				}
+				continue
+			}
+			values[i] = converted
+		}
+		result = append(result, values)
+	}
+	return result
+}
+
+func columnType(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return typeBigint
+	case uint64:
+		return typeNumeric
+	case float64:
+		return typeDouble
+	case bool:
+		return typeBoolean
+	default:
+		return typeText
+	}
+}
+
+// widen returns the type of a column of the type have, to also store values
+// of the type want, or false if the column can not be changed to fit both.
+func widen(have, want string) (string, bool) {
+	if have == typeBigint && (want == typeNumeric || want == typeDouble) {
+		return want, true
+	}
+	return have, false
+}
+
+// convert returns the value to store in a column of the type, or false if
+// the value does not fit the type.
+func convert(typ string, value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case int64:
+		switch typ {
+		case typeBigint, typeNumeric:
+			return v, true
+		case typeDouble:
+			return float64(v), true
+		}
+	case uint64:
+		switch typ {
+		case typeNumeric:
+			return v, true
+		case typeBigint:
+			return int64(v), v <= math.MaxInt64
+		case typeDouble:
+			return float64(v), true
+		}
+	case float64:
+		return v, typ == typeDouble || typ == typeNumeric
+	case bool:
+		return v, typ == typeBoolean
+	case string:
+		return v, typ == typeText
+	case map[string]string, map[string]interface{}:
+		return v, typ == typeJSONB
+	default:
+		// The time and tag id columns.
+		return v, true
+	}
+	return nil, false
+}
+
+// tagID returns an id of the tag set of the metric.  It's a hash of the tags
+// like m.HashID(), but without the measurement name as each measurement has
+// its own tag table.
+func tagID(m telegraf.Metric) int64 {
+	h := fnv.New64a()
+	for _, tag := range m.TagList() {
+		h.Write([]byte(tag.Key))
+		h.Write([]byte{0})
+		h.Write([]byte(tag.Value))
+		h.Write([]byte{0})
+	}
+	var sum [8]byte
+	return int64(binary.BigEndian.Uint64(h.Sum(sum[:0])))
+}
@@ -0,0 +1,62 @@
+package sql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertSQL(t *testing.T) {
+	row := []column{{name: "timestamp"}, {name: "host"}, {name: `we"ird`}}
+	tests := []struct {
+		driver   string
+		expected string
+	}{
+		{driver: "sqlite3", expected: `INSERT INTO "cpu"("timestamp","host","we""ird") VALUES(?,?,?)`},
+		{driver: "pgx", expected: `INSERT INTO "cpu"("timestamp","host","we""ird") VALUES($1,$2,$3)`},
+		{driver: "mysql", expected: "INSERT INTO `cpu`(`timestamp`,`host`,`we\"ird`) VALUES(?,?,?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			s := &SQL{Driver: tt.driver}
+			require.Equal(t, tt.expected, s.insertSQL("cpu", row))
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	require.Equal(t, uint64(math.MaxInt64), value(uint64(math.MaxInt64)))
+	require.Equal(t, "18446744073709551615", value(uint64(math.MaxUint64)))
+	require.Equal(t, int64(-1), value(int64(-1)))
+	require.Equal(t, "up", value("up"))
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		value    interface{}
+		expected interface{}
+		ok       bool
+	}{
+		{name: "integer as real", typ: "DOUBLE PRECISION", value: int64(3), expected: 3.0, ok: true},
+		{name: "whole float as integer", typ: "BIGINT", value: 2.0, expected: int64(2), ok: true},
+		{name: "fraction as integer", typ: "BIGINT", value: 2.5, ok: false},
+		{name: "large unsigned as integer", typ: "INT8", value: uint64(math.MaxUint64), ok: false},
+		{name: "large unsigned as numeric", typ: "NUMERIC(20)", value: uint64(math.MaxUint64), expected: uint64(math.MaxUint64), ok: true},
+		{name: "bool as tinyint", typ: "TINYINT", value: true, expected: int64(1), ok: true},
+		{name: "string as integer", typ: "BIGINT", value: "up", ok: false},
+		{name: "integer as text", typ: "varchar(255)", value: int64(1), ok: false},
+		{name: "unknown type", typ: "JSON", value: "{}", expected: "{}", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := convert(kindOf(tt.typ), tt.value)
+			require.Equal(t, tt.ok, ok)
+			if ok {
+				require.Equal(t, tt.expected, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// +build cgo
+
+package sql
+
+// The SQLite driver is a cgo package.
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,202 @@
+// +build cgo
+
+package sql
+
+import (
+	gosql "database/sql"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLite(t *testing.T) (*SQL, func()) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+
+	s := outputs.Outputs["sql"]().(*SQL)
+	s.Driver = "sqlite3"
+	s.DataSourceName = "file:" + filepath.Join(dir, "metrics.db")
+	s.Log = testutil.Logger{}
+	require.NoError(t, s.Connect())
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func query(t *testing.T, db *gosql.DB, q string) [][]interface{} {
+	rows, err := db.Query(q)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...))
+		result = append(result, values)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestWrite(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	tm := time.Unix(1591012800, 0)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 42.5, "count": 3, "ok": true, "state": "up"},
+			tm,
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 12.0, "count": 4, "ok": false, "state": "down"},
+			tm,
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(1024)},
+			tm,
+		),
+	}
+	require.NoError(t, s.Write(metrics))
+
+	rows := query(t, s.db, `SELECT "cpu", "host", "count", "ok", "state", "usage" FROM "cpu" ORDER BY "host"`)
+	require.Equal(t, [][]interface{}{
+		{"cpu0", "a", int64(3), true, "up", 42.5},
+		{"cpu0", "b", int64(4), false, "down", 12.0},
+	}, rows)
+
+	rows = query(t, s.db, `SELECT "timestamp", "host", "used" FROM "mem"`)
+	require.Len(t, rows, 1)
+	require.True(t, tm.Equal(rows[0][0].(time.Time)))
+	require.Equal(t, []interface{}{"a", int64(1024)}, rows[0][1:])
+}
+
+func TestAddColumns(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"path": "/"},
+			map[string]interface{}{"used": 1},
+			time.Unix(0, 0),
+		),
+	}))
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"path": "/home", "fstype": "ext4"},
+			map[string]interface{}{"used": 2, "free": 3},
+			time.Unix(10, 0),
+		),
+	}))
+
+	// Existing tables are used after a restart.
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Connect())
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"path": "/var"},
+			map[string]interface{}{"used": 4, "inodes": 5},
+			time.Unix(20, 0),
+		),
+	}))
+
+	rows := query(t, s.db, `SELECT "path", "fstype", "used", "free", "inodes" FROM "disk" ORDER BY "timestamp"`)
+	require.Equal(t, [][]interface{}{
+		{"/", nil, int64(1), nil, nil},
+		{"/home", "ext4", int64(2), int64(3), nil},
+		{"/var", nil, int64(4), nil, int64(5)},
+	}, rows)
+}
+
+func TestWriteRollback(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	_, err := s.db.Exec(`CREATE TABLE "cpu"("timestamp" TIMESTAMP, "usage" DOUBLE PRECISION CHECK ("usage" <= 100))`)
+	require.NoError(t, err)
+
+	err = s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 50.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 150.0}, time.Unix(10, 0)),
+	})
+	require.Error(t, err)
+	require.Empty(t, query(t, s.db, `SELECT * FROM "cpu"`))
+}
+
+func TestWriteLargeUnsigned(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("net",
+			map[string]string{},
+			map[string]interface{}{"bytes": uint64(math.MaxUint64)},
+			time.Unix(0, 0),
+		),
+	}))
+
+	// SQLite stores numbers beyond its integer range as floats.
+	rows := query(t, s.db, `SELECT "bytes" FROM "net"`)
+	require.Len(t, rows, 1)
+	require.Equal(t, float64(math.MaxUint64), rows[0][0])
+}
+
+func TestWriteExistingColumnTypes(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	_, err := s.db.Exec(`CREATE TABLE "cpu"("timestamp" TIMESTAMP, "count" BIGINT, "usage" DOUBLE PRECISION)`)
+	require.NoError(t, err)
+
+	// Values not fitting the column types are left out instead of failing
+	// the batch.
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"count": 1, "usage": 2}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"count": 2.0, "usage": 2.5}, time.Unix(10, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"count": 2.5, "usage": "high"}, time.Unix(20, 0)),
+	}))
+
+	rows := query(t, s.db, `SELECT "count", "usage" FROM "cpu" ORDER BY "timestamp"`)
+	require.Equal(t, [][]interface{}{
+		{int64(1), 2.0},
+		{int64(2), 2.5},
+		{nil, nil},
+	}, rows)
+}
+
+func TestTableQueryError(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	// Only a missing table leads to creating it.
+	s.TableExistsTemplate = `SELECT missing FROM {TABLE}`
+	_, err := s.db.Exec(`CREATE TABLE "cpu"("timestamp" TIMESTAMP)`)
+	require.NoError(t, err)
+
+	err = s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "querying table")
+}
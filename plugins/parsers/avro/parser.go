@@ -0,0 +1,268 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// magicByte is the first byte of every message in the Confluent wire format,
+// it is followed by the 4 byte big-endian schema id.
+const magicByte = 0x00
+
+var (
+	// ErrNoMetric is returned when no metric is found in input line
+	ErrNoMetric = errors.New("no metric in line")
+)
+
+type TimeFunc func() time.Time
+
+type Config struct {
+	MetricName      string
+	SchemaRegistry  string
+	Schema          string
+	Measurement     string
+	Tags            []string
+	Fields          []string
+	Timestamp       string
+	TimestampFormat string
+	Timezone        string
+	FieldSeparator  string
+	DefaultTags     map[string]string
+	TimeFunc        TimeFunc
+}
+
+// Parser decodes Avro encoded records into metrics, either using the
+// Confluent wire format with a schema registry or using a static schema.
+type Parser struct {
+	*Config
+
+	schema   *schema
+	registry *schemaRegistry
+}
+
+// NewParser returns a new Avro parser, the schema registry takes precedence
+// over the static schema if both are given.
+func NewParser(c *Config) (*Parser, error) {
+	p := &Parser{Config: c}
+
+	switch {
+	case c.SchemaRegistry != "":
+		p.registry = newSchemaRegistry(c.SchemaRegistry)
+	case c.Schema != "":
+		schema, err := newSchema(c.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("compiling avro_schema: %v", err)
+		}
+		p.schema = schema
+	default:
+		return nil, errors.New("one of avro_schema_registry or avro_schema must be set")
+	}
+
+	if c.TimestampFormat == "" {
+		c.TimestampFormat = "unix"
+	}
+	if c.FieldSeparator == "" {
+		c.FieldSeparator = "_"
+	}
+	if c.TimeFunc == nil {
+		c.TimeFunc = time.Now
+	}
+
+	return p, nil
+}
+
+func (p *Parser) SetTimeFunc(fn TimeFunc) {
+	p.TimeFunc = fn
+}
+
+// Parse decodes a single Avro record into a metric.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) == 0 {
+		return []telegraf.Metric{}, nil
+	}
+
+	schema, data, err := p.schemaFor(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	native, _, err := schema.codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding avro record: %v", err)
+	}
+
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected avro record, got %T", native)
+	}
+
+	m, err := p.createMetric(schema, record)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+// ParseLine delegates a single record to the Parse function.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+
+	return metrics[0], nil
+}
+
+// SetDefaultTags sets the default tags for every metric
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// schemaFor returns the schema to decode buf with and the Avro payload with
+// any wire format header removed.
+func (p *Parser) schemaFor(buf []byte) (*schema, []byte, error) {
+	if p.registry == nil {
+		return p.schema, buf, nil
+	}
+
+	if len(buf) < 5 {
+		return nil, nil, fmt.Errorf("message too short for wire format: %d bytes", len(buf))
+	}
+	if buf[0] != magicByte {
+		return nil, nil, fmt.Errorf("unknown magic byte: %#x", buf[0])
+	}
+
+	id := int32(binary.BigEndian.Uint32(buf[1:5]))
+	schema, err := p.registry.getSchema(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema, buf[5:], nil
+}
+
+func (p *Parser) createMetric(s *schema, record map[string]interface{}) (telegraf.Metric, error) {
+	values := make(map[string]interface{})
+	p.flatten(s, values, "", record, s.root, "")
+
+	name := p.MetricName
+	if p.Measurement != "" {
+		if v, ok := values[p.Measurement]; ok {
+			name = toString(v)
+			delete(values, p.Measurement)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, key := range p.Tags {
+		if v, ok := values[key]; ok {
+			tags[key] = toString(v)
+			delete(values, key)
+		}
+	}
+
+	tm := p.TimeFunc()
+	if p.Timestamp != "" {
+		if v, ok := values[p.Timestamp]; ok {
+			var err error
+			tm, err = p.parseTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			delete(values, p.Timestamp)
+		}
+	}
+
+	if len(p.Fields) > 0 {
+		selected := make(map[string]interface{}, len(p.Fields))
+		for _, key := range p.Fields {
+			if v, ok := values[key]; ok {
+				selected[key] = v
+			}
+		}
+		values = selected
+	}
+
+	fields := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if ts, ok := v.(time.Time); ok {
+			v = ts.UnixNano()
+		}
+		fields[k] = v
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	// Timestamps using the timestamp-millis or timestamp-micros logical
+	// types are already decoded by the codec.
+	if ts, ok := v.(time.Time); ok {
+		return ts, nil
+	}
+	return internal.ParseTimestamp(p.TimestampFormat, v, p.Timezone)
+}
+
+// setValue sets the field to the decoded value of a primitive type.  Values
+// not matching their schema, which the codec never returns, are flattened by
+// their shape.
+func (p *Parser) setValue(values map[string]interface{}, key string, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, inner := range val {
+			p.setValue(values, p.join(key, k), inner)
+		}
+	case []interface{}:
+		for i, inner := range val {
+			p.setValue(values, p.join(key, strconv.Itoa(i)), inner)
+		}
+	case int32:
+		values[key] = int64(val)
+	case float32:
+		values[key] = float64(val)
+	case []byte:
+		values[key] = string(val)
+	case *big.Rat:
+		// The decimal logical type.
+		f, _ := val.Float64()
+		values[key] = f
+	case time.Duration:
+		// The time-millis and time-micros logical types.
+		values[key] = int64(val)
+	default:
+		values[key] = val
+	}
+}
+
+func (p *Parser) join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + p.FieldSeparator + key
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
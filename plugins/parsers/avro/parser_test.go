@@ -0,0 +1,324 @@
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+{
+  "type": "record",
+  "name": "Value",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "measurement", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "up", "type": "boolean"},
+    {"name": "value", "type": ["null", "double"], "default": null},
+    {"name": "count", "type": "int"},
+    {"name": "timestamp", "type": "long"},
+    {"name": "disk", "type": {
+      "type": "record",
+      "name": "Disk",
+      "fields": [
+        {"name": "free", "type": "long"},
+        {"name": "used", "type": "float"}
+      ]
+    }}
+  ]
+}
+`
+
+var DefaultTime = func() time.Time {
+	return time.Unix(42, 0)
+}
+
+func testRecord() map[string]interface{} {
+	return map[string]interface{}{
+		"measurement": "cpu",
+		"host":        "server01",
+		"up":          true,
+		"value":       goavro.Union("double", 42.5),
+		"count":       int32(7),
+		"timestamp":   int64(1592846400),
+		"disk": map[string]interface{}{
+			"free": int64(1024),
+			"used": float32(0.5),
+		},
+	}
+}
+
+func encode(t *testing.T, schema string, record map[string]interface{}) []byte {
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+	buf, err := codec.BinaryFromNative(nil, record)
+	require.NoError(t, err)
+	return buf
+}
+
+func wireFormat(id uint32, payload []byte) []byte {
+	buf := make([]byte, 5, 5+len(payload))
+	buf[0] = magicByte
+	binary.BigEndian.PutUint32(buf[1:], id)
+	return append(buf, payload...)
+}
+
+func TestNewParserRequiresSchema(t *testing.T) {
+	_, err := NewParser(&Config{MetricName: "avro"})
+	require.Error(t, err)
+}
+
+func TestNewParserInvalidSchema(t *testing.T) {
+	_, err := NewParser(&Config{MetricName: "avro", Schema: `{"type": "bogus"}`})
+	require.Error(t, err)
+}
+
+func TestParseStaticSchema(t *testing.T) {
+	p, err := NewParser(&Config{
+		MetricName:  "avro",
+		Schema:      testSchema,
+		Measurement: "measurement",
+		Tags:        []string{"host"},
+		Timestamp:   "timestamp",
+		TimeFunc:    DefaultTime,
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(encode(t, testSchema, testRecord()))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "server01",
+			},
+			map[string]interface{}{
+				"up":        true,
+				"value":     42.5,
+				"count":     int64(7),
+				"disk_free": int64(1024),
+				"disk_used": float64(0.5),
+			},
+			time.Unix(1592846400, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSelectedFields(t *testing.T) {
+	p, err := NewParser(&Config{
+		MetricName:     "avro",
+		Schema:         testSchema,
+		Tags:           []string{"host", "measurement"},
+		Fields:         []string{"value", "disk.free"},
+		FieldSeparator: ".",
+		TimeFunc:       DefaultTime,
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(encode(t, testSchema, testRecord()))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"avro",
+			map[string]string{
+				"host":        "server01",
+				"measurement": "cpu",
+			},
+			map[string]interface{}{
+				"value":     42.5,
+				"disk.free": int64(1024),
+			},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseNullUnion(t *testing.T) {
+	p, err := NewParser(&Config{
+		MetricName: "avro",
+		Schema:     testSchema,
+		Fields:     []string{"value", "count"},
+		TimeFunc:   DefaultTime,
+	})
+	require.NoError(t, err)
+
+	record := testRecord()
+	record["value"] = nil
+	metrics, err := p.Parse(encode(t, testSchema, record))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"count": int64(7)}, metrics[0].Fields())
+}
+
+func TestParseUnions(t *testing.T) {
+	schema := `
+{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "time", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}]},
+    {"name": "rec", "type": ["null", {
+      "type": "record",
+      "name": "Rec",
+      "fields": [{"name": "a", "type": "long"}]
+    }]},
+    {"name": "again", "type": ["null", "Rec"]},
+    {"name": "choice", "type": ["null", "long", "string"]},
+    {"name": "single", "type": {
+      "type": "record",
+      "name": "Single",
+      "fields": [{"name": "string", "type": "string"}]
+    }},
+    {"name": "labels", "type": {"type": "map", "values": ["null", "long"]}}
+  ]
+}
+`
+	p, err := NewParser(&Config{
+		MetricName: "avro",
+		Schema:     schema,
+		TimeFunc:   DefaultTime,
+	})
+	require.NoError(t, err)
+
+	record := map[string]interface{}{
+		"time":   goavro.Union("long.timestamp-millis", time.Unix(1, 0)),
+		"rec":    goavro.Union("com.example.Rec", map[string]interface{}{"a": int64(1)}),
+		"again":  goavro.Union("com.example.Rec", map[string]interface{}{"a": int64(2)}),
+		"choice": goavro.Union("string", "x"),
+		"single": map[string]interface{}{"string": "s"},
+		"labels": map[string]interface{}{"k": goavro.Union("long", int64(3)), "n": nil},
+	}
+	metrics, err := p.Parse(encode(t, schema, record))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{
+		"time":          int64(1e9),
+		"rec_a":         int64(1),
+		"again_a":       int64(2),
+		"choice":        "x",
+		"single_string": "s",
+		"labels_k":      int64(3),
+	}, metrics[0].Fields())
+}
+
+func TestParseLogicalTimestamp(t *testing.T) {
+	schema := `
+{
+  "type": "record",
+  "name": "Event",
+  "fields": [
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "value", "type": "long"}
+  ]
+}
+`
+	p, err := NewParser(&Config{
+		MetricName: "avro",
+		Schema:     schema,
+		Timestamp:  "time",
+		TimeFunc:   DefaultTime,
+	})
+	require.NoError(t, err)
+
+	record := map[string]interface{}{
+		"time":  time.Unix(1592846400, 123000000),
+		"value": int64(1),
+	}
+	metrics, err := p.Parse(encode(t, schema, record))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, int64(1592846400123000000), metrics[0].Time().UnixNano())
+}
+
+func TestParseInvalidTimestamp(t *testing.T) {
+	p, err := NewParser(&Config{
+		MetricName:      "avro",
+		Schema:          testSchema,
+		Timestamp:       "host",
+		TimestampFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	_, err = p.Parse(encode(t, testSchema, testRecord()))
+	require.Error(t, err)
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		fmt.Fprintf(w, `{"schema": %s}`, strconv.Quote(testSchema))
+	}))
+	defer ts.Close()
+
+	p, err := NewParser(&Config{
+		MetricName:     "avro",
+		SchemaRegistry: ts.URL + "/",
+		Measurement:    "measurement",
+		Tags:           []string{"host"},
+		Fields:         []string{"count"},
+		Timestamp:      "timestamp",
+	})
+	require.NoError(t, err)
+
+	msg := wireFormat(7, encode(t, testSchema, testRecord()))
+	for i := 0; i < 3; i++ {
+		metrics, err := p.Parse(msg)
+		require.NoError(t, err)
+
+		expected := []telegraf.Metric{
+			testutil.MustMetric(
+				"cpu",
+				map[string]string{
+					"host": "server01",
+				},
+				map[string]interface{}{
+					"count": int64(7),
+				},
+				time.Unix(1592846400, 0),
+			),
+		}
+		testutil.RequireMetricsEqual(t, expected, metrics)
+	}
+
+	// The schema is only fetched once and then served from the cache.
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	_, err = p.Parse(wireFormat(8, encode(t, testSchema, testRecord())))
+	require.Error(t, err)
+}
+
+func TestParseWireFormatErrors(t *testing.T) {
+	p, err := NewParser(&Config{
+		MetricName:     "avro",
+		SchemaRegistry: "http://127.0.0.1:1",
+	})
+	require.NoError(t, err)
+
+	_, err = p.Parse([]byte{0x00, 0x01})
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x02})
+	require.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package avro
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schema is a compiled schema along with its parsed definition, which is
+// needed to tell unions apart from records and maps in the decoded values.
+type schema struct {
+	codec *goavro.Codec
+	root  interface{}
+	// named holds the definitions of the named types by full name.
+	named map[string]map[string]interface{}
+}
+
+func newSchema(text string) (*schema, error) {
+	codec, err := goavro.NewCodec(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if err := json.Unmarshal([]byte(text), &root); err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		codec: codec,
+		root:  root,
+		named: make(map[string]map[string]interface{}),
+	}
+	s.register(root, "")
+	return s, nil
+}
+
+// register collects the named types defined in the definition.
+func (s *schema) register(def interface{}, namespace string) {
+	switch d := def.(type) {
+	case []interface{}:
+		for _, branch := range d {
+			s.register(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch d["type"] {
+		case "record", "error", "enum", "fixed":
+			name := fullName(d, namespace)
+			s.named[name] = d
+			namespace = namespaceOf(name)
+		}
+		if fields, ok := d["fields"].([]interface{}); ok {
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok {
+					s.register(f["type"], namespace)
+				}
+			}
+		}
+		s.register(d["items"], namespace)
+		s.register(d["values"], namespace)
+		if _, ok := d["type"].(string); !ok {
+			s.register(d["type"], namespace)
+		}
+	}
+}
+
+// resolve returns the definition of the named type referenced by name, or
+// nil for primitive types.
+func (s *schema) resolve(name, namespace string) (map[string]interface{}, string) {
+	if !strings.Contains(name, ".") && namespace != "" {
+		if d, ok := s.named[namespace+"."+name]; ok {
+			return d, namespace
+		}
+	}
+	if d, ok := s.named[name]; ok {
+		return d, namespaceOf(name)
+	}
+	return nil, namespace
+}
+
+// branch returns the definition of the union member with the type name used
+// by the codec as key of the decoded union value.
+func (s *schema) branch(union []interface{}, key, namespace string) interface{} {
+	var nonNull []interface{}
+	for _, b := range union {
+		if s.typeName(b, namespace) == key {
+			return b
+		}
+		if b != "null" {
+			nonNull = append(nonNull, b)
+		}
+	}
+	if len(nonNull) == 1 {
+		return nonNull[0]
+	}
+	return nil
+}
+
+// typeName returns the name of the type of the definition as used by the
+// codec for union values: the full name of named types, the type with the
+// logical type for logical types, and the type otherwise.
+func (s *schema) typeName(def interface{}, namespace string) string {
+	switch d := def.(type) {
+	case string:
+		if named, ns := s.resolve(d, namespace); named != nil {
+			return fullName(named, ns)
+		}
+		return d
+	case map[string]interface{}:
+		t, _ := d["type"].(string)
+		switch t {
+		case "record", "error", "enum", "fixed":
+			return fullName(d, namespace)
+		}
+		if lt, ok := d["logicalType"].(string); ok {
+			return t + "." + lt
+		}
+		return s.typeName(d["type"], namespace)
+	}
+	return ""
+}
+
+// flatten converts the natively decoded value of the definition into field
+// values.  Nested records, maps and arrays are joined with the field
+// separator, unions are unwrapped and null values are skipped.
+func (p *Parser) flatten(s *schema, values map[string]interface{}, prefix string, v interface{}, def interface{}, namespace string) {
+	if v == nil {
+		return
+	}
+
+	switch d := def.(type) {
+	case string:
+		if named, ns := s.resolve(d, namespace); named != nil {
+			p.flatten(s, values, prefix, v, named, ns)
+			return
+		}
+	case []interface{}:
+		union, ok := v.(map[string]interface{})
+		if !ok || len(union) != 1 {
+			break
+		}
+		for key, inner := range union {
+			p.flatten(s, values, prefix, inner, s.branch(d, key, namespace), namespace)
+		}
+		return
+	case map[string]interface{}:
+		switch d["type"] {
+		case "record", "error":
+			record, ok := v.(map[string]interface{})
+			if !ok {
+				break
+			}
+			ns := namespaceOf(fullName(d, namespace))
+			fields, _ := d["fields"].([]interface{})
+			for _, field := range fields {
+				f, ok := field.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := f["name"].(string)
+				p.flatten(s, values, p.join(prefix, name), record[name], f["type"], ns)
+			}
+			return
+		case "array":
+			if items, ok := v.([]interface{}); ok {
+				for i, inner := range items {
+					p.flatten(s, values, p.join(prefix, strconv.Itoa(i)), inner, d["items"], namespace)
+				}
+				return
+			}
+		case "map":
+			if m, ok := v.(map[string]interface{}); ok {
+				for k, inner := range m {
+					p.flatten(s, values, p.join(prefix, k), inner, d["values"], namespace)
+				}
+				return
+			}
+		case "enum", "fixed":
+		default:
+			if _, ok := d["logicalType"]; !ok {
+				p.flatten(s, values, prefix, v, d["type"], namespace)
+				return
+			}
+		}
+	}
+
+	p.setValue(values, prefix, v)
+}
+
+func fullName(def map[string]interface{}, namespace string) string {
+	name, _ := def["name"].(string)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if ns, ok := def["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func namespaceOf(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemaRegistry fetches schemas by id from a Confluent compatible schema
+// registry and caches the compiled schemas.  Schemas in the registry are
+// immutable so cached entries never need to be refreshed.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[int32]*schema
+}
+
+func newSchemaRegistry(url string) *schemaRegistry {
+	return &schemaRegistry{
+		url: strings.TrimRight(url, "/"),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		cache: make(map[int32]*schema),
+	}
+}
+
+// getSchema returns the schema with the given id, fetching it from the
+// registry if it is not yet cached.
+func (r *schemaRegistry) getSchema(id int32) (*schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.cache[id]; ok {
+		return s, nil
+	}
+
+	text, err := r.fetch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSchema(text)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema %d: %v", id, err)
+	}
+
+	r.cache[id] = s
+	return s, nil
+}
+
+func (r *schemaRegistry) fetch(id int32) (string, error) {
+	url := fmt.Sprintf("%s/schemas/ids/%d", r.url, id)
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("fetching schema %d: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching schema %d: received status code %d (%s)",
+			id, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding schema %d: %v", id, err)
+	}
+	return body.Schema, nil
+}
@@ -13,6 +13,10 @@ import (
 
 const (
 	maxErrorBufferSize = 1024
+
+	// maxPartialErrors is the number of line errors kept by a
+	// PartialParseError, further errors are only counted.
+	maxPartialErrors = 100
 )
 
 var (
@@ -56,6 +60,37 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("metric parse error: %s at %d:%d: %q", e.msg, e.LineNumber, e.Column, buffer)
 }
 
+// PartialParseError is returned when parsing a stream in which one or more
+// lines could not be parsed.  The malformed lines are skipped and all other
+// metrics are still returned.
+type PartialParseError struct {
+	// Errors holds the errors of the first malformed lines.
+	Errors []*ParseError
+	// Count is the total number of malformed lines.
+	Count int
+}
+
+func (e *PartialParseError) add(err *ParseError) {
+	e.Count++
+	if len(e.Errors) < maxPartialErrors {
+		e.Errors = append(e.Errors, err)
+	}
+}
+
+// Error summarizes the parse errors using the first error and the number of
+// other errors.
+func (e *PartialParseError) Error() string {
+	first := e.Errors[0].Error()
+	switch e.Count {
+	case 1:
+		return first
+	case 2:
+		return fmt.Sprintf("%s (and 1 other parse error)", first)
+	default:
+		return fmt.Sprintf("%s (and %d other parse errors)", first, e.Count-1)
+	}
+}
+
 // Parser is an InfluxDB Line Protocol parser that implements the
 // parsers.Parser interface.
 type Parser struct {
@@ -125,6 +160,22 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	return metrics, nil
 }
 
+// ParseReader parses line protocol from r without reading the whole input
+// into memory, calling fn for each metric.  Unlike Parse, malformed lines are
+// skipped; if any lines were skipped a *PartialParseError is returned once
+// the end of the input is reached.  Errors reading from r or returned by fn
+// stop parsing and are returned as is.
+func (p *Parser) ParseReader(r io.Reader, fn func(telegraf.Metric) error) error {
+	sp := NewStreamParser(r)
+	sp.handler.SetTimeFunc(p.handler.timeFunc)
+	sp.handler.SetTimePrecision(p.handler.timePrecision)
+
+	return sp.ParseAll(func(m telegraf.Metric) error {
+		p.applyDefaultTagsSingle(m)
+		return fn(m)
+	})
+}
+
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	metrics, err := p.Parse([]byte(line))
 	if err != nil {
@@ -217,6 +268,41 @@ func (p *StreamParser) Next() (telegraf.Metric, error) {
 	return metric, nil
 }
 
+// ParseAll parses the remaining stream calling fn for each metric.  Lines
+// that cannot be parsed are skipped and reported in a *PartialParseError
+// once the end of the stream is reached.  Errors reading the stream or
+// returned by fn stop parsing and are returned as is.
+func (p *StreamParser) ParseAll(fn func(telegraf.Metric) error) error {
+	var partial *PartialParseError
+	for {
+		m, err := p.Next()
+		if err == EOF {
+			break
+		}
+
+		if parseErr, ok := err.(*ParseError); ok {
+			if partial == nil {
+				partial = &PartialParseError{}
+			}
+			partial.add(parseErr)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+
+	if partial != nil {
+		return partial
+	}
+	return nil
+}
+
 // Position returns the current byte offset into the data.
 func (p *StreamParser) Position() int {
 	return p.machine.Position()
@@ -912,3 +912,108 @@ func TestStreamParserProducesAllAvailableMetrics(t *testing.T) {
 	_, err = parser.Next()
 	require.NoError(t, err)
 }
+
+func TestStreamParserParseAll(t *testing.T) {
+	parser := NewStreamParser(strings.NewReader(
+		"cpu value=42\ncpu value=invalid\ncpu value=43\nfoo value=1asdf2.0\nfoo value=2asdf2.0\n"))
+	parser.SetTimeFunc(DefaultTime)
+
+	var metrics []telegraf.Metric
+	err := parser.ParseAll(func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+
+	partial, ok := err.(*PartialParseError)
+	require.True(t, ok)
+	require.Equal(t, 3, partial.Count)
+	require.Len(t, partial.Errors, 3)
+	require.Equal(t, 2, partial.Errors[0].LineNumber)
+	require.Equal(t, 4, partial.Errors[1].LineNumber)
+	require.Equal(t, 5, partial.Errors[2].LineNumber)
+	require.Equal(t,
+		`metric parse error: expected field at 2:11: "cpu value=" (and 2 other parse errors)`,
+		err.Error())
+
+	expected := []telegraf.Metric{
+		Metric(metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, DefaultTime())),
+		Metric(metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 43.0}, DefaultTime())),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestStreamParserParseAllCallbackError(t *testing.T) {
+	parser := NewStreamParser(strings.NewReader("cpu value=42\ncpu value=43\n"))
+
+	stopErr := errors.New("stop")
+	var count int
+	err := parser.ParseAll(func(m telegraf.Metric) error {
+		count++
+		return stopErr
+	})
+	require.Equal(t, stopErr, err)
+	require.Equal(t, 1, count)
+}
+
+func TestPartialParseErrorLimit(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < maxPartialErrors+10; i++ {
+		input.WriteString("cpu value=invalid\n")
+	}
+	input.WriteString("cpu value=42\n")
+
+	parser := NewStreamParser(&input)
+	var count int
+	err := parser.ParseAll(func(m telegraf.Metric) error {
+		count++
+		return nil
+	})
+
+	partial, ok := err.(*PartialParseError)
+	require.True(t, ok)
+	require.Equal(t, maxPartialErrors+10, partial.Count)
+	require.Len(t, partial.Errors, maxPartialErrors)
+	require.Equal(t, 1, count)
+}
+
+func TestParserParseReader(t *testing.T) {
+	handler := NewMetricHandler()
+	parser := NewParser(handler)
+	parser.SetTimeFunc(DefaultTime)
+	parser.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	var metrics []telegraf.Metric
+	add := func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	}
+
+	err := parser.ParseReader(strings.NewReader("cpu value=42\ncpu value=\ncpu,host=a value=43 0\n"), add)
+	require.Error(t, err)
+	require.IsType(t, &PartialParseError{}, err)
+
+	expected := []telegraf.Metric{
+		Metric(metric.New("cpu", map[string]string{"host": "localhost"}, map[string]interface{}{"value": 42.0}, DefaultTime())),
+		Metric(metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 43.0}, time.Unix(0, 0))),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	metrics = nil
+	err = parser.ParseReader(strings.NewReader("cpu value=42\n"), add)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+}
+
+func TestParserParseReaderError(t *testing.T) {
+	readerErr := errors.New("error but not eof")
+
+	parser := NewParser(NewMetricHandler())
+	err := parser.ParseReader(&MockReader{
+		ReadF: func(p []byte) (int, error) {
+			return 0, readerErr
+		},
+	}, func(m telegraf.Metric) error {
+		return nil
+	})
+	require.Equal(t, readerErr, err)
+}
@@ -0,0 +1,202 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// timestampExtension is the MessagePack extension type reserved for
+// timestamps.
+const timestampExtension = -1
+
+var (
+	// ErrNoMetric is returned when no metric is found in input line
+	ErrNoMetric = errors.New("no metric in line")
+)
+
+type TimeFunc func() time.Time
+
+// Parser decodes metrics written by the msgpack serializer.  The input may
+// contain any number of consecutive metrics.
+type Parser struct {
+	MetricName  string
+	DefaultTags map[string]string
+	TimeFunc    TimeFunc
+}
+
+func NewParser(metricName string, defaultTags map[string]string) *Parser {
+	return &Parser{
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}
+}
+
+func (p *Parser) SetTimeFunc(fn TimeFunc) {
+	p.TimeFunc = fn
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		var m telegraf.Metric
+		var err error
+		m, buf, err = p.readMetric(buf)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) readMetric(b []byte) (telegraf.Metric, []byte, error) {
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading metric: %v", err)
+	}
+
+	name := p.MetricName
+	tm := p.TimeFunc()
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+
+	for i := uint32(0); i < sz; i++ {
+		var key string
+		key, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading metric key: %v", err)
+		}
+
+		switch key {
+		case "name":
+			name, b, err = msgp.ReadStringBytes(b)
+		case "time":
+			tm, b, err = readTime(b)
+		case "tags":
+			b, err = readTags(b, tags)
+		case "fields":
+			b, err = readFields(b, fields)
+		default:
+			b, err = msgp.Skip(b)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %q: %v", key, err)
+		}
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	m, err := metric.New(name, tags, fields, tm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, b, nil
+}
+
+func readTags(b []byte, tags map[string]string) ([]byte, error) {
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < sz; i++ {
+		var key, value string
+		key, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		value, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return b, nil
+}
+
+func readFields(b []byte, fields map[string]interface{}) ([]byte, error) {
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < sz; i++ {
+		var key string
+		key, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		value, b, err = msgp.ReadIntfBytes(b)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case int64, uint64, float64, bool, string:
+			fields[key] = v
+		case float32:
+			fields[key] = float64(v)
+		case []byte:
+			fields[key] = string(v)
+		}
+	}
+	return b, nil
+}
+
+// readTime decodes any of the 32, 64 and 96 bit formats of the timestamp
+// extension.
+func readTime(b []byte) (time.Time, []byte, error) {
+	ext := msgp.RawExtension{Type: timestampExtension}
+	b, err := msgp.ReadExtensionBytes(b, &ext)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	data := ext.Data
+	switch len(data) {
+	case 4:
+		sec := binary.BigEndian.Uint32(data)
+		return time.Unix(int64(sec), 0), b, nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		nsec := int64(v >> 34)
+		sec := int64(v & 0x3ffffffff)
+		return time.Unix(sec, nsec), b, nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data[:4])
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return time.Unix(sec, int64(nsec)), b, nil
+	default:
+		return time.Time{}, nil, fmt.Errorf("invalid timestamp length %d", len(data))
+	}
+}
@@ -0,0 +1,155 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var DefaultTime = func() time.Time {
+	return time.Unix(42, 0)
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "server01",
+			},
+			map[string]interface{}{
+				"float":  42.5,
+				"int":    int64(-3),
+				"small":  int64(7),
+				"uint":   uint64(7),
+				"max":    uint64(18446744073709551615),
+				"bool":   true,
+				"string": "ok",
+			},
+			time.Unix(1592846400, 123456789),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{
+				"used": int64(1024),
+			},
+			time.Unix(1592846410, 0),
+		),
+	}
+
+	buf, err := msgpack.NewSerializer().SerializeBatch(expected)
+	require.NoError(t, err)
+
+	p := NewParser("msgpack", nil)
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host": "server01",
+		},
+		map[string]interface{}{
+			"value": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+	buf, err := msgpack.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	p := NewParser("msgpack", map[string]string{"host": "default", "dc": "west"})
+	actual, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"host": "server01", "dc": "west"}, actual.Tags())
+}
+
+func TestParseTimestampFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		ext      []byte
+		expected time.Time
+	}{
+		{
+			name:     "timestamp 32",
+			ext:      []byte{0xd6, 0xff, 0x5e, 0xf0, 0xe8, 0x40},
+			expected: time.Unix(1592846400, 0),
+		},
+		{
+			name:     "timestamp 64",
+			ext:      []byte{0xd7, 0xff, 0x00, 0x00, 0x00, 0x04, 0x5e, 0xf0, 0xe8, 0x40},
+			expected: time.Unix(1592846400, 1),
+		},
+		{
+			name:     "timestamp 96",
+			ext:      []byte{0xc7, 0x0c, 0xff, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x5e, 0xf0, 0xe8, 0x40},
+			expected: time.Unix(1592846400, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := msgp.AppendMapHeader(nil, 2)
+			buf = msgp.AppendString(buf, "time")
+			buf = append(buf, tt.ext...)
+			buf = msgp.AppendString(buf, "fields")
+			buf = msgp.AppendMapHeader(buf, 1)
+			buf = msgp.AppendString(buf, "value")
+			buf = msgp.AppendFloat64(buf, 1.0)
+
+			p := NewParser("msgpack", nil)
+			metrics, err := p.Parse(buf)
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, "msgpack", metrics[0].Name())
+			require.True(t, tt.expected.Equal(metrics[0].Time()))
+		})
+	}
+}
+
+func TestParseMissingTime(t *testing.T) {
+	buf := msgp.AppendMapHeader(nil, 2)
+	buf = msgp.AppendString(buf, "name")
+	buf = msgp.AppendString(buf, "cpu")
+	buf = msgp.AppendString(buf, "fields")
+	buf = msgp.AppendMapHeader(buf, 1)
+	buf = msgp.AppendString(buf, "value")
+	buf = msgp.AppendFloat64(buf, 1.0)
+
+	p := NewParser("msgpack", nil)
+	p.SetTimeFunc(DefaultTime)
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, DefaultTime(), metrics[0].Time())
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := NewParser("msgpack", nil)
+
+	_, err := p.Parse([]byte("cpu value=42"))
+	require.Error(t, err)
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	buf, err := msgpack.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+	_, err = p.Parse(buf[:len(buf)-2])
+	require.Error(t, err)
+}
+
+func TestParseEmpty(t *testing.T) {
+	p := NewParser("msgpack", nil)
+	metrics, err := p.Parse(nil)
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+
+	_, err = p.ParseLine("")
+	require.Equal(t, ErrNoMetric, err)
+}
@@ -2,8 +2,10 @@ package parsers
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -13,6 +15,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
@@ -56,6 +59,16 @@ type Parser interface {
 	SetDefaultTags(tags map[string]string)
 }
 
+// ReaderParser is an optional interface for parsers that can parse directly
+// from a stream without reading the whole input into memory.
+type ReaderParser interface {
+	// ParseReader parses all metrics from the reader, passing each to fn
+	// as soon as it is parsed so the metrics do not have to be held in
+	// memory.  An error may be returned after some metrics were passed if
+	// only part of the input could be parsed.
+	ParseReader(r io.Reader, fn func(telegraf.Metric) error) error
+}
+
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
@@ -148,6 +161,17 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// avro configuration
+	AvroSchemaRegistry  string   `toml:"avro_schema_registry"`
+	AvroSchema          string   `toml:"avro_schema"`
+	AvroMeasurement     string   `toml:"avro_measurement"`
+	AvroTags            []string `toml:"avro_tags"`
+	AvroFields          []string `toml:"avro_fields"`
+	AvroTimestamp       string   `toml:"avro_timestamp"`
+	AvroTimestampFormat string   `toml:"avro_timestamp_format"`
+	AvroTimezone        string   `toml:"avro_timezone"`
+	AvroFieldSeparator  string   `toml:"avro_field_separator"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -232,6 +256,24 @@ func NewParser(config *Config) (Parser, error) {
 			config.DefaultTags,
 			config.FormUrlencodedTagKeys,
 		)
+	case "msgpack":
+		parser, err = NewMsgpackParser(config.MetricName, config.DefaultTags)
+	case "avro":
+		parser, err = avro.NewParser(
+			&avro.Config{
+				MetricName:      config.MetricName,
+				SchemaRegistry:  config.AvroSchemaRegistry,
+				Schema:          config.AvroSchema,
+				Measurement:     config.AvroMeasurement,
+				Tags:            config.AvroTags,
+				Fields:          config.AvroFields,
+				Timestamp:       config.AvroTimestamp,
+				TimestampFormat: config.AvroTimestampFormat,
+				Timezone:        config.AvroTimezone,
+				FieldSeparator:  config.AvroFieldSeparator,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -324,6 +366,11 @@ func NewLogFmtParser(metricName string, defaultTags map[string]string) (Parser,
 	return logfmt.NewParser(metricName, defaultTags), nil
 }
 
+// NewMsgpackParser returns a parser for metrics in the msgpack format.
+func NewMsgpackParser(metricName string, defaultTags map[string]string) (Parser, error) {
+	return msgpack.NewParser(metricName, defaultTags), nil
+}
+
 func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
 	return wavefront.NewWavefrontParser(defaultTags), nil
 }
@@ -0,0 +1,157 @@
+package branch
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Each case selects metrics using the namepass, namedrop, tagpass and
+  ## tagdrop filters and runs them through its own processors.  Metrics are
+  ## handled by the first matching case only.
+  [[processors.branch.case]]
+    namepass = ["cpu"]
+
+    [[processors.branch.case.processors.rename]]
+      [[processors.branch.case.processors.rename.replace]]
+        field = "usage_idle"
+        dest = "idle"
+
+  [[processors.branch.case]]
+    [processors.branch.case.tagpass]
+      env = ["dev"]
+
+    [[processors.branch.case.processors.override]]
+      name_override = "dev_metrics"
+
+  ## Processors for metrics not matched by any case, without a default
+  ## branch these metrics are passed on unchanged.
+  [processors.branch.default]
+    [[processors.branch.default.processors.override]]
+      name_suffix = "_other"
+`
+
+// Branch runs each metric through the processor chain of the first matching
+// case, or through the default chain.
+type Branch struct {
+	Log telegraf.Logger `toml:"-"`
+
+	cases    []*models.ProcessorBranch
+	fallback models.RunningProcessors
+
+	// accs are the accumulators passed to the first processor of each chain,
+	// they forward the metrics through the rest of the chain.
+	accs map[*models.RunningProcessor]telegraf.Accumulator
+}
+
+func (b *Branch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *Branch) Description() string {
+	return "Run metrics through different processors depending on filters"
+}
+
+func (b *Branch) SetBranches(cases []*models.ProcessorBranch, fallback models.RunningProcessors) {
+	b.cases = cases
+	b.fallback = fallback
+}
+
+// Branches returns the processor chains of the cases and the default branch.
+func (b *Branch) Branches() ([]*models.ProcessorBranch, models.RunningProcessors) {
+	return b.cases, b.fallback
+}
+
+func (b *Branch) Init() error {
+	for _, chain := range b.chains() {
+		for _, rp := range chain {
+			if err := rp.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %w", rp.LogName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Start starts the processors of all chains, from last to first so that no
+// processor emits metrics into a processor that was not started yet.
+func (b *Branch) Start(acc telegraf.Accumulator) error {
+	b.accs = make(map[*models.RunningProcessor]telegraf.Accumulator)
+
+	var started []*models.RunningProcessor
+	for _, chain := range b.chains() {
+		next := acc
+		for i := len(chain) - 1; i >= 0; i-- {
+			rp := chain[i]
+			if err := rp.Start(next); err != nil {
+				for _, p := range started {
+					p.Stop()
+				}
+				return fmt.Errorf("starting processor %s: %w", rp.LogName(), err)
+			}
+			started = append(started, rp)
+			b.accs[rp] = next
+			next = &stage{Accumulator: acc, processor: rp, next: next}
+		}
+	}
+	return nil
+}
+
+func (b *Branch) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
+	chain := b.fallback
+	for _, c := range b.cases {
+		if c.Filter.Select(metric) {
+			chain = c.Processors
+			break
+		}
+	}
+
+	if len(chain) == 0 {
+		acc.AddMetric(metric)
+		return nil
+	}
+	return chain[0].Add(metric, b.accs[chain[0]])
+}
+
+// Stop stops the processors of all chains, from first to last so that the
+// metrics flushed by a processor are still handled by the following ones.
+func (b *Branch) Stop() error {
+	for _, chain := range b.chains() {
+		for _, rp := range chain {
+			rp.Stop()
+		}
+	}
+	return nil
+}
+
+func (b *Branch) chains() []models.RunningProcessors {
+	chains := make([]models.RunningProcessors, 0, len(b.cases)+1)
+	for _, c := range b.cases {
+		chains = append(chains, c.Processors)
+	}
+	return append(chains, b.fallback)
+}
+
+// stage is the accumulator of a processor within a chain, metrics are passed
+// to the next processor.  All other methods use the accumulator of the branch
+// processor directly.
+type stage struct {
+	telegraf.Accumulator
+	processor *models.RunningProcessor
+	next      telegraf.Accumulator
+}
+
+func (s *stage) AddMetric(metric telegraf.Metric) {
+	if err := s.processor.Add(metric, s.next); err != nil {
+		s.AddError(err)
+	}
+}
+
+func init() {
+	processors.AddStreaming("branch", func() telegraf.StreamingProcessor {
+		return &Branch{}
+	})
+}
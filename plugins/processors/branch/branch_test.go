@@ -0,0 +1,177 @@
+package branch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// suffix appends a suffix to the metric name.
+type suffix struct {
+	suffix string
+}
+
+func (s *suffix) SampleConfig() string { return "" }
+func (s *suffix) Description() string  { return "" }
+
+func (s *suffix) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.SetName(m.Name() + s.suffix)
+	}
+	return in
+}
+
+// buffer holds all metrics until it is stopped.
+type buffer struct {
+	acc     telegraf.Accumulator
+	metrics []telegraf.Metric
+}
+
+func (b *buffer) SampleConfig() string { return "" }
+func (b *buffer) Description() string  { return "" }
+
+func (b *buffer) Start(acc telegraf.Accumulator) error {
+	b.acc = acc
+	return nil
+}
+
+func (b *buffer) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	b.metrics = append(b.metrics, m)
+	return nil
+}
+
+func (b *buffer) Stop() error {
+	for _, m := range b.metrics {
+		b.acc.AddMetric(m)
+	}
+	return nil
+}
+
+func newProcessor(p telegraf.StreamingProcessor, order int64) *models.RunningProcessor {
+	return models.NewRunningProcessor(p, &models.ProcessorConfig{Name: "test", Order: order})
+}
+
+func newSuffix(s string, order int64) *models.RunningProcessor {
+	return newProcessor(processors.NewStreamingProcessorFromProcessor(&suffix{suffix: s}), order)
+}
+
+func newFilter(t *testing.T, f models.Filter) models.Filter {
+	require.NoError(t, f.Compile())
+	return f
+}
+
+func metric(name string, tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric(name, tags, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+}
+
+func run(t *testing.T, b *Branch, in ...telegraf.Metric) []telegraf.Metric {
+	acc := &testutil.Accumulator{}
+	require.NoError(t, b.Init())
+	require.NoError(t, b.Start(acc))
+	for _, m := range in {
+		require.NoError(t, b.Add(m, acc))
+	}
+	require.NoError(t, b.Stop())
+	return acc.GetTelegrafMetrics()
+}
+
+func TestFirstMatchingCase(t *testing.T) {
+	b := &Branch{}
+	b.SetBranches(
+		[]*models.ProcessorBranch{
+			{
+				Filter:     newFilter(t, models.Filter{NamePass: []string{"cpu"}}),
+				Processors: models.RunningProcessors{newSuffix("_a", 0), newSuffix("_b", 1)},
+			},
+			{
+				Filter: newFilter(t, models.Filter{TagPass: []models.TagFilter{
+					{Name: "env", Filter: []string{"dev"}},
+				}}),
+				Processors: models.RunningProcessors{newSuffix("_dev", 0)},
+			},
+		},
+		models.RunningProcessors{newSuffix("_other", 0)},
+	)
+
+	actual := run(t, b,
+		metric("cpu", map[string]string{"env": "dev"}),
+		metric("mem", map[string]string{"env": "dev"}),
+		metric("disk", map[string]string{"env": "prod"}),
+	)
+
+	expected := []telegraf.Metric{
+		metric("cpu_a_b", map[string]string{"env": "dev"}),
+		metric("mem_dev", map[string]string{"env": "dev"}),
+		metric("disk_other", map[string]string{"env": "prod"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestWithoutDefault(t *testing.T) {
+	b := &Branch{}
+	b.SetBranches(
+		[]*models.ProcessorBranch{
+			{
+				Filter:     newFilter(t, models.Filter{NamePass: []string{"cpu"}}),
+				Processors: models.RunningProcessors{newSuffix("_a", 0)},
+			},
+			{
+				// A case without processors passes the metrics on.
+				Filter: newFilter(t, models.Filter{NamePass: []string{"mem"}}),
+			},
+		},
+		nil,
+	)
+
+	actual := run(t, b,
+		metric("cpu", nil),
+		metric("mem", nil),
+		metric("disk", nil),
+	)
+
+	expected := []telegraf.Metric{
+		metric("cpu_a", nil),
+		metric("mem", nil),
+		metric("disk", nil),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestProcessorFilter(t *testing.T) {
+	rp := newSuffix("_a", 0)
+	rp.Config.Filter = newFilter(t, models.Filter{NamePass: []string{"cpu"}})
+
+	b := &Branch{}
+	b.SetBranches(nil, models.RunningProcessors{rp, newSuffix("_b", 1)})
+
+	actual := run(t, b,
+		metric("cpu", nil),
+		metric("mem", nil),
+	)
+
+	expected := []telegraf.Metric{
+		metric("cpu_a_b", nil),
+		metric("mem_b", nil),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestStopFlushesThroughChain(t *testing.T) {
+	b := &Branch{}
+	b.SetBranches(nil, models.RunningProcessors{
+		newProcessor(&buffer{}, 0),
+		newSuffix("_a", 1),
+	})
+
+	actual := run(t, b, metric("cpu", nil))
+
+	expected := []telegraf.Metric{
+		metric("cpu_a", nil),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
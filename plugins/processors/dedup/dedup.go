@@ -1,9 +1,11 @@
 package dedup
 
 import (
+	"math"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/processors"
 )
@@ -11,12 +13,33 @@ import (
 var sampleConfig = `
   ## Maximum time to suppress output
   dedup_interval = "600s"
+
+  ## Fields to compare, glob patterns are supported.  Other fields are passed
+  ## through with the metric but do not cause it to be emitted.  By default
+  ## all fields are compared.
+  # fields = []
+
+  ## Numeric values are only considered changed if they differ from the last
+  ## emitted value by more than the absolute deadband and by more than
+  ## deadband_percent percent of the last emitted value.
+  # deadband = 0.0
+  # deadband_percent = 0.0
+
+  ## Emit only the compared fields that changed instead of the whole metric.
+  ## The whole metric is still emitted at least every dedup_interval.
+  # changed_fields_only = false
 `
 
 type Dedup struct {
-	DedupInterval internal.Duration `toml:"dedup_interval"`
-	FlushTime     time.Time
-	Cache         map[uint64]telegraf.Metric
+	DedupInterval     internal.Duration `toml:"dedup_interval"`
+	Fields            []string          `toml:"fields"`
+	Deadband          float64           `toml:"deadband"`
+	DeadbandPercent   float64           `toml:"deadband_percent"`
+	ChangedFieldsOnly bool              `toml:"changed_fields_only"`
+	FlushTime         time.Time
+	Cache             map[uint64]telegraf.Metric
+
+	fieldFilter filter.Filter
 }
 
 func (d *Dedup) SampleConfig() string {
@@ -27,6 +50,12 @@ func (d *Dedup) Description() string {
 	return "Filter metrics with repeating field values"
 }
 
+func (d *Dedup) Init() error {
+	var err error
+	d.fieldFilter, err = filter.Compile(d.Fields)
+	return err
+}
+
 // Remove single item from slice
 func remove(slice []telegraf.Metric, i int) []telegraf.Metric {
 	slice[len(slice)-1], slice[i] = slice[i], slice[len(slice)-1]
@@ -55,6 +84,35 @@ func (d *Dedup) save(metric telegraf.Metric, id uint64) {
 	d.Cache[id].Accept()
 }
 
+// Check if the value differs from the cached one
+func (d *Dedup) differs(cached, value interface{}) bool {
+	if d.Deadband == 0 && d.DeadbandPercent == 0 {
+		return cached != value
+	}
+	x, ok := toFloat(cached)
+	if !ok {
+		return cached != value
+	}
+	y, ok := toFloat(value)
+	if !ok {
+		return cached != value
+	}
+	delta := math.Abs(y - x)
+	return delta > d.Deadband && delta > math.Abs(x)*d.DeadbandPercent/100
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
 // main processing method
 func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 	for idx, metric := range metrics {
@@ -76,13 +134,23 @@ func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 		// For each field compare value with the cached one
 		changed := false
 		added := false
+		unchanged := make([]string, 0)
+		updated := make([]*telegraf.Field, 0)
 		sametime := metric.Time() == m.Time()
 		for _, f := range metric.FieldList() {
+			if d.fieldFilter != nil && !d.fieldFilter.Match(f.Key) {
+				continue
+			}
 			if value, ok := m.GetField(f.Key); ok {
-				if value != f.Value {
-					changed = true
+				if !d.differs(value, f.Value) {
+					unchanged = append(unchanged, f.Key)
+					continue
+				}
+				changed = true
+				if !d.ChangedFieldsOnly {
 					break
 				}
+				updated = append(updated, f)
 			} else if sametime {
 				// This field isn't in the cached metric but it's the
 				// same series and timestamp. Merge it into the cached
@@ -98,8 +166,24 @@ func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 
 				m.AddField(f.Key, f.Value)
 				added = true
+			} else if d.ChangedFieldsOnly {
+				// New field, emit it like a changed one.
+				changed = true
+				updated = append(updated, f)
+			}
+		}
+		// Only emit the changed fields, the cached metric keeps the time
+		// of the last full emission for the next heartbeat
+		if changed && d.ChangedFieldsOnly {
+			for _, f := range updated {
+				m.AddField(f.Key, f.Value)
+			}
+			for _, key := range unchanged {
+				metric.RemoveField(key)
 			}
+			continue
 		}
+
 		// If any field value has changed then refresh the cache
 		if changed {
 			d.save(metric, id)
@@ -9,6 +9,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
 )
 
 func createMetric(name string, value int64, when time.Time) telegraf.Metric {
@@ -192,3 +193,173 @@ func TestSameTimestamp(t *testing.T) {
 	out = dedup.Apply(in)
 	require.Equal(t, []telegraf.Metric{}, out) // drop
 }
+
+func TestDeadband(t *testing.T) {
+	tests := []struct {
+		name            string
+		deadband        float64
+		deadbandPercent float64
+		value           float64
+		passed          bool
+	}{
+		{name: "within absolute", deadband: 0.5, value: 100.4, passed: false},
+		{name: "outside absolute", deadband: 0.5, value: 99.4, passed: true},
+		{name: "within relative", deadbandPercent: 1, value: 101, passed: false},
+		{name: "outside relative", deadbandPercent: 1, value: 101.5, passed: true},
+		{name: "within both", deadband: 2, deadbandPercent: 1, value: 101.5, passed: false},
+		{name: "outside both", deadband: 2, deadbandPercent: 1, value: 102.5, passed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			dedup := createDedup(now)
+			dedup.Deadband = tt.deadband
+			dedup.DeadbandPercent = tt.deadbandPercent
+			require.NoError(t, dedup.Init())
+
+			in, _ := metric.New("metric",
+				map[string]string{"tag": "value"},
+				map[string]interface{}{"value": 100.0},
+				now.Add(-1*time.Second),
+			)
+			dedup.Apply(in)
+
+			in, _ = metric.New("metric",
+				map[string]string{"tag": "value"},
+				map[string]interface{}{"value": tt.value},
+				now,
+			)
+			out := dedup.Apply(in)
+			if tt.passed {
+				require.Equal(t, []telegraf.Metric{in}, out)
+			} else {
+				require.Equal(t, []telegraf.Metric{}, out)
+			}
+		})
+	}
+}
+
+func TestDeadbandComparesLastEmitted(t *testing.T) {
+	now := time.Now()
+	dedup := createDedup(now)
+	dedup.Deadband = 1
+	require.NoError(t, dedup.Init())
+
+	// Small steps are suppressed until they add up to more than the deadband.
+	var passed []float64
+	for i, v := range []float64{10, 10.6, 10.8, 11.2, 11.4} {
+		in, _ := metric.New("metric",
+			map[string]string{},
+			map[string]interface{}{"value": v},
+			now.Add(time.Duration(i-5)*time.Second),
+		)
+		for _, m := range dedup.Apply(in) {
+			v, _ := m.GetField("value")
+			passed = append(passed, v.(float64))
+		}
+	}
+	require.Equal(t, []float64{10, 11.2}, passed)
+}
+
+func TestFields(t *testing.T) {
+	now := time.Now()
+	dedup := createDedup(now)
+	dedup.Fields = []string{"value*"}
+	require.NoError(t, dedup.Init())
+
+	in, _ := metric.New("metric",
+		map[string]string{},
+		map[string]interface{}{"value": 1, "uptime": 10},
+		now.Add(-1*time.Second),
+	)
+	dedup.Apply(in)
+
+	// Changes of other fields are ignored.
+	in, _ = metric.New("metric",
+		map[string]string{},
+		map[string]interface{}{"value": 1, "uptime": 11},
+		now,
+	)
+	out := dedup.Apply(in)
+	require.Equal(t, []telegraf.Metric{}, out)
+}
+
+func TestChangedFieldsOnly(t *testing.T) {
+	now := time.Now()
+	dedup := createDedup(now)
+	dedup.Fields = []string{"a", "b", "c"}
+	dedup.ChangedFieldsOnly = true
+	require.NoError(t, dedup.Init())
+
+	in := testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"a": 1, "b": 2, "uptime": 10},
+		now.Add(-3*time.Second),
+	)
+	out := dedup.Apply(in)
+	require.Equal(t, []telegraf.Metric{in}, out)
+
+	in = testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"a": 1, "b": 3, "uptime": 11},
+		now.Add(-2*time.Second),
+	)
+	out = dedup.Apply(in)
+	expected := testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"b": 3, "uptime": 11},
+		now.Add(-2*time.Second),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, out)
+
+	// New fields are emitted as changed.
+	in = testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"a": 1, "b": 3, "c": 4, "uptime": 12},
+		now.Add(-1*time.Second),
+	)
+	out = dedup.Apply(in)
+	expected = testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"c": 4, "uptime": 12},
+		now.Add(-1*time.Second),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, out)
+
+	in = testutil.MustMetric("metric",
+		map[string]string{"tag": "value"},
+		map[string]interface{}{"a": 1, "b": 3, "c": 4, "uptime": 13},
+		now,
+	)
+	out = dedup.Apply(in)
+	require.Equal(t, []telegraf.Metric{}, out)
+}
+
+func TestChangedFieldsOnlyHeartbeat(t *testing.T) {
+	now := time.Now()
+	dedup := createDedup(now)
+	dedup.ChangedFieldsOnly = true
+	require.NoError(t, dedup.Init())
+
+	first := testutil.MustMetric("metric",
+		map[string]string{},
+		map[string]interface{}{"a": 1, "b": 2},
+		now.Add(-9*time.Minute),
+	)
+	dedup.Apply(first)
+
+	in := testutil.MustMetric("metric",
+		map[string]string{},
+		map[string]interface{}{"a": 1, "b": 3},
+		now.Add(-5*time.Minute),
+	)
+	out := dedup.Apply(in)
+	require.Len(t, out, 1)
+
+	// The cache keeps the time of the last full emission, so the whole
+	// metric is emitted again dedup_interval after it.
+	cached := dedup.Cache[first.HashID()]
+	require.Equal(t, first.Time(), cached.Time())
+	b, _ := cached.GetField("b")
+	require.Equal(t, int64(3), b)
+}
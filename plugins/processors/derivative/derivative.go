@@ -0,0 +1,231 @@
+package derivative
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/counters"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const (
+	modeRate  = "rate"
+	modeDelta = "delta"
+)
+
+var sampleConfig = `
+  ## Fields to compute, glob patterns are supported.
+  fields = ["*"]
+
+  ## Either "rate" for the change per second or "delta" for the change since
+  ## the previous value.
+  # mode = "rate"
+
+  ## Keep the raw counter and add the computed value as a new field with the
+  ## suffix, or replace the raw counter with the computed value.
+  # keep_original = false
+
+  ## Suffix of the computed field when keep_original is set, defaults to
+  ## "_rate" or "_delta" depending on the mode.
+  # suffix = "_rate"
+
+  ## Size of the counters in bits, either 32 or 64.  When set, the rate or
+  ## delta is computed across a wraparound of a counter decreasing from the
+  ## upper half of its range.  Otherwise a decreasing counter is taken as
+  ## reset and its field is left out until the next value.
+  # counter_size = 0
+
+  ## Maximum time between two values of a series, if the gap is larger the
+  ## previous value is discarded.  Series that are not updated within this
+  ## time are removed.
+  # max_gap = "5m"
+`
+
+type Derivative struct {
+	Fields       []string          `toml:"fields"`
+	Mode         string            `toml:"mode"`
+	KeepOriginal bool              `toml:"keep_original"`
+	Suffix       string            `toml:"suffix"`
+	CounterSize  int               `toml:"counter_size"`
+	MaxGap       internal.Duration `toml:"max_gap"`
+
+	fieldFilter filter.Filter
+	wraparound  counters.Wraparound
+	cache       map[uint64]*series
+	lastCleanup time.Time
+}
+
+// series holds the previous values of the fields of one series.
+type series struct {
+	values   map[string]sample
+	lastSeen time.Time
+}
+
+type sample struct {
+	value interface{}
+	time  time.Time
+}
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Compute the rate or delta of counter fields"
+}
+
+func (d *Derivative) Init() error {
+	switch d.Mode {
+	case "":
+		d.Mode = modeRate
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("invalid mode %q", d.Mode)
+	}
+
+	if d.Suffix == "" {
+		d.Suffix = "_" + d.Mode
+	}
+
+	var err error
+	d.wraparound, err = counters.NewWraparound(d.CounterSize)
+	if err != nil {
+		return fmt.Errorf("counter_size: %v", err)
+	}
+
+	fields := d.Fields
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	d.fieldFilter, err = filter.Compile(fields)
+	if err != nil {
+		return fmt.Errorf("compiling fields: %v", err)
+	}
+
+	d.cache = make(map[uint64]*series)
+	d.lastCleanup = time.Now()
+	return nil
+}
+
+func (d *Derivative) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if d.apply(metric) {
+			out = append(out, metric)
+		} else {
+			metric.Drop()
+		}
+	}
+	d.cleanup()
+	return out
+}
+
+// apply updates the metric in place and reports if it still has fields.
+func (d *Derivative) apply(metric telegraf.Metric) bool {
+	id := metric.HashID()
+	s, ok := d.cache[id]
+	if !ok {
+		s = &series{values: make(map[string]sample)}
+		d.cache[id] = s
+	}
+	s.lastSeen = time.Now()
+
+	// The field list is modified while iterating, so work on a copy.
+	fields := make([]telegraf.Field, 0, len(metric.FieldList()))
+	for _, field := range metric.FieldList() {
+		if d.fieldFilter.Match(field.Key) && counters.IsNumeric(field.Value) {
+			fields = append(fields, *field)
+		}
+	}
+
+	tm := metric.Time()
+	for _, field := range fields {
+		key, value := field.Key, field.Value
+		prev, ok := s.values[key]
+		if ok && !tm.After(prev.time) {
+			// Out of order or duplicate value, keep the newer previous value.
+			if !d.KeepOriginal {
+				metric.RemoveField(key)
+			}
+			continue
+		}
+		s.values[key] = sample{value: value, time: tm}
+
+		var result interface{}
+		if ok && (d.MaxGap.Duration == 0 || tm.Sub(prev.time) <= d.MaxGap.Duration) {
+			result, ok = d.compute(prev, value, tm)
+		} else {
+			ok = false
+		}
+
+		switch {
+		case !ok && !d.KeepOriginal:
+			metric.RemoveField(key)
+		case !ok:
+		case d.KeepOriginal:
+			metric.AddField(key+d.Suffix, result)
+		default:
+			metric.AddField(key, result)
+		}
+	}
+	return len(metric.FieldList()) > 0
+}
+
+// compute returns the rate or delta between the previous and the current
+// value, it fails if the counter was reset.
+func (d *Derivative) compute(prev sample, value interface{}, tm time.Time) (interface{}, bool) {
+	var delta interface{}
+	switch v := value.(type) {
+	case float64:
+		p := counters.ToFloat(prev.value)
+		if v < p {
+			return nil, false
+		}
+		delta = v - p
+	default:
+		cur, curOk := counters.ToUint(value)
+		p, prevOk := counters.ToUint(prev.value)
+		if !curOk || !prevOk {
+			return nil, false
+		}
+		diff, ok := d.wraparound.Diff(p, cur)
+		if !ok {
+			return nil, false
+		}
+		if diff > math.MaxInt64 {
+			return nil, false
+		}
+		delta = int64(diff)
+	}
+
+	if d.Mode == modeDelta {
+		return delta, true
+	}
+	elapsed := tm.Sub(prev.time).Seconds()
+	return counters.ToFloat(delta) / elapsed, true
+}
+
+// cleanup removes series that have not been updated within max_gap.
+func (d *Derivative) cleanup() {
+	if d.MaxGap.Duration == 0 || time.Since(d.lastCleanup) < d.MaxGap.Duration {
+		return
+	}
+	d.lastCleanup = time.Now()
+	for id, s := range d.cache {
+		if time.Since(s.lastSeen) > d.MaxGap.Duration {
+			delete(d.cache, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("derivative", func() telegraf.Processor {
+		return &Derivative{
+			MaxGap: internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
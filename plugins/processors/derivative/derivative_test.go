@@ -0,0 +1,186 @@
+package derivative
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}, sec int64) telegraf.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		fields,
+		time.Unix(sec, 0),
+	)
+}
+
+func TestRate(t *testing.T) {
+	d := &Derivative{Fields: []string{"bytes_*"}}
+	require.NoError(t, d.Init())
+
+	out := d.Apply(newMetric(map[string]interface{}{"bytes_recv": int64(100), "drop": int64(1)}, 0))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{"drop": int64(1)}, 0),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+
+	out = d.Apply(newMetric(map[string]interface{}{"bytes_recv": int64(300), "drop": int64(2)}, 10))
+	expected = []telegraf.Metric{
+		newMetric(map[string]interface{}{"bytes_recv": 20.0, "drop": int64(2)}, 10),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestDeltaKeepOriginal(t *testing.T) {
+	d := &Derivative{Mode: "delta", KeepOriginal: true}
+	require.NoError(t, d.Init())
+
+	d.Apply(newMetric(map[string]interface{}{"packets": uint64(10), "load": 1.5}, 0))
+	out := d.Apply(newMetric(map[string]interface{}{"packets": uint64(15), "load": 2.0}, 5))
+
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{
+			"packets":       uint64(15),
+			"packets_delta": int64(5),
+			"load":          2.0,
+			"load_delta":    0.5,
+		}, 5),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestFirstValueDropsMetric(t *testing.T) {
+	d := &Derivative{}
+	require.NoError(t, d.Init())
+
+	out := d.Apply(newMetric(map[string]interface{}{"bytes": int64(1)}, 0))
+	require.Empty(t, out)
+}
+
+func TestCounterReset(t *testing.T) {
+	d := &Derivative{Mode: "delta"}
+	require.NoError(t, d.Init())
+
+	d.Apply(newMetric(map[string]interface{}{"bytes": int64(1000)}, 0))
+	out := d.Apply(newMetric(map[string]interface{}{"bytes": int64(10)}, 10))
+	require.Empty(t, out)
+
+	// The value after the reset is used as the new base.
+	out = d.Apply(newMetric(map[string]interface{}{"bytes": int64(30)}, 20))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{"bytes": int64(20)}, 20),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestWraparound(t *testing.T) {
+	tests := []struct {
+		name        string
+		counterSize int
+		prev        uint64
+		cur         uint64
+		expected    []telegraf.Metric
+	}{
+		{
+			name:        "32 bit",
+			counterSize: 32,
+			prev:        math.MaxUint32 - 9,
+			cur:         5,
+			expected: []telegraf.Metric{
+				newMetric(map[string]interface{}{"octets": int64(15)}, 10),
+			},
+		},
+		{
+			name:        "64 bit",
+			counterSize: 64,
+			prev:        math.MaxUint64 - 4,
+			cur:         5,
+			expected: []telegraf.Metric{
+				newMetric(map[string]interface{}{"octets": int64(10)}, 10),
+			},
+		},
+		{
+			name:        "decrease from lower half is a reset",
+			counterSize: 32,
+			prev:        1000,
+			cur:         5,
+			expected:    []telegraf.Metric{},
+		},
+		{
+			name:        "value larger than counter size is a reset",
+			counterSize: 32,
+			prev:        math.MaxUint32 + 10,
+			cur:         5,
+			expected:    []telegraf.Metric{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Derivative{Mode: "delta", CounterSize: tt.counterSize}
+			require.NoError(t, d.Init())
+
+			d.Apply(newMetric(map[string]interface{}{"octets": tt.prev}, 0))
+			out := d.Apply(newMetric(map[string]interface{}{"octets": tt.cur}, 10))
+			testutil.RequireMetricsEqual(t, tt.expected, out)
+		})
+	}
+}
+
+func TestMaxGap(t *testing.T) {
+	d := &Derivative{MaxGap: internal.Duration{Duration: time.Minute}}
+	require.NoError(t, d.Init())
+
+	d.Apply(newMetric(map[string]interface{}{"bytes": int64(100)}, 0))
+	out := d.Apply(newMetric(map[string]interface{}{"bytes": int64(200)}, 120))
+	require.Empty(t, out)
+
+	out = d.Apply(newMetric(map[string]interface{}{"bytes": int64(260)}, 150))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{"bytes": 2.0}, 150),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestOutOfOrder(t *testing.T) {
+	d := &Derivative{Mode: "delta", KeepOriginal: true}
+	require.NoError(t, d.Init())
+
+	d.Apply(newMetric(map[string]interface{}{"bytes": int64(100)}, 10))
+	out := d.Apply(newMetric(map[string]interface{}{"bytes": int64(50)}, 5))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{"bytes": int64(50)}, 5),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestSeparateSeries(t *testing.T) {
+	d := &Derivative{Mode: "delta"}
+	require.NoError(t, d.Init())
+
+	eth1 := func(v int64, sec int64) telegraf.Metric {
+		return testutil.MustMetric("net",
+			map[string]string{"interface": "eth1"},
+			map[string]interface{}{"bytes": v},
+			time.Unix(sec, 0),
+		)
+	}
+
+	d.Apply(newMetric(map[string]interface{}{"bytes": int64(100)}, 0), eth1(1000, 0))
+	out := d.Apply(newMetric(map[string]interface{}{"bytes": int64(110)}, 10), eth1(1500, 10))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{"bytes": int64(10)}, 10),
+		eth1(500, 10),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestInitErrors(t *testing.T) {
+	require.Error(t, (&Derivative{Mode: "integral"}).Init())
+	require.Error(t, (&Derivative{CounterSize: 16}).Init())
+	require.Error(t, (&Derivative{Fields: []string{"["}}).Init())
+}
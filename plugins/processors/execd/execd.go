@@ -5,15 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
-	"github.com/influxdata/telegraf/internal/process"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 const sampleConfig = `
@@ -23,24 +23,54 @@ const sampleConfig = `
 
   ## Delay before the process is restarted after an unexpected termination
   restart_delay = "10s"
+
+  ## Number of processes to run.  Metrics of the same series are always sent
+  ## to the same process, so their order is kept.
+  # workers = 1
+
+  ## Wait for the output of each metric before sending the next one.  The
+  ## program must write an empty line after the output of every metric it
+  ## reads, including metrics it drops.
+  # wait_for_response = false
+
+  ## Maximum time to wait for the output of a metric, only used with
+  ## wait_for_response.
+  # timeout = "5s"
+
+  ## Action if the timeout expires, "drop" to drop the metric or "pass" to
+  ## pass the metric on unchanged.
+  # timeout_action = "drop"
 `
 
+// queueSize is the number of metrics buffered for each worker.
+const queueSize = 100
+
 type Execd struct {
-	Command      []string        `toml:"command"`
-	RestartDelay config.Duration `toml:"restart_delay"`
-	Log          telegraf.Logger
+	Command         []string        `toml:"command"`
+	RestartDelay    config.Duration `toml:"restart_delay"`
+	Workers         int             `toml:"workers"`
+	WaitForResponse bool            `toml:"wait_for_response"`
+	Timeout         config.Duration `toml:"timeout"`
+	TimeoutAction   string          `toml:"timeout_action"`
+	Log             telegraf.Logger
 
 	parserConfig     *parsers.Config
-	parser           parsers.Parser
 	serializerConfig *serializers.Config
-	serializer       serializers.Serializer
 	acc              telegraf.Accumulator
-	process          *process.Process
+	workers          []*worker
+	wg               sync.WaitGroup
+
+	inFlight     selfstat.Stat
+	timeouts     selfstat.Stat
+	responseTime selfstat.Stat
 }
 
 func New() *Execd {
 	return &Execd{
-		RestartDelay: config.Duration(10 * time.Second),
+		RestartDelay:  config.Duration(10 * time.Second),
+		Workers:       1,
+		Timeout:       config.Duration(5 * time.Second),
+		TimeoutAction: "drop",
 		parserConfig: &parsers.Config{
 			DataFormat: "influx",
 		},
@@ -59,80 +89,54 @@ func (e *Execd) Description() string {
 }
 
 func (e *Execd) Start(acc telegraf.Accumulator) error {
-	var err error
-	e.parser, err = parsers.NewParser(e.parserConfig)
-	if err != nil {
-		return fmt.Errorf("error creating parser: %w", err)
-	}
-	e.serializer, err = serializers.NewSerializer(e.serializerConfig)
-	if err != nil {
-		return fmt.Errorf("error creating serializer: %w", err)
-	}
 	e.acc = acc
 
-	e.process, err = process.New(e.Command)
-	if err != nil {
-		return fmt.Errorf("error creating new process: %w", err)
-	}
-	e.process.Log = e.Log
-	e.process.RestartDelay = time.Duration(e.RestartDelay)
-	e.process.ReadStdoutFn = e.cmdReadOut
-	e.process.ReadStderrFn = e.cmdReadErr
-
-	if err = e.process.Start(); err != nil {
-		// if there was only one argument, and it contained spaces, warn the user
-		// that they may have configured it wrong.
-		if len(e.Command) == 1 && strings.Contains(e.Command[0], " ") {
-			e.Log.Warn("The processors.execd Command contained spaces but no arguments. " +
-				"This setting expects the program and arguments as an array of strings, " +
-				"not as a space-delimited string. See the plugin readme for an example.")
+	tags := map[string]string{"command": e.Command[0]}
+	e.inFlight = selfstat.Register("execd", "metrics_in_flight", tags)
+	e.timeouts = selfstat.Register("execd", "timeouts", tags)
+	e.responseTime = selfstat.RegisterTiming("execd", "response_time_ns", tags)
+
+	e.workers = make([]*worker, 0, e.Workers)
+	for i := 0; i < e.Workers; i++ {
+		w, err := e.newWorker()
+		if err == nil {
+			err = w.start()
+		}
+		if err != nil {
+			e.Stop()
+			return err
 		}
-		return fmt.Errorf("failed to start process %s: %w", e.Command, err)
+		e.workers = append(e.workers, w)
+
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			w.run()
+		}()
 	}
 
 	return nil
 }
 
+// Add queues the metric for the worker of its series.
 func (e *Execd) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
-	b, err := e.serializer.Serialize(m)
-	if err != nil {
-		return fmt.Errorf("metric serializing error: %w", err)
-	}
-
-	_, err = e.process.Stdin.Write(b)
-	if err != nil {
-		return fmt.Errorf("error writing to process stdin: %w", err)
-	}
-
-	// We cannot maintain tracking metrics at the moment because input/output
-	// is done asynchronously and we don't have any metric metadata to tie the
-	// output metric back to the original input metric.
-	m.Drop()
+	e.inFlight.Incr(1)
+	w := e.workers[m.HashID()%uint64(len(e.workers))]
+	w.queue <- m
 	return nil
 }
 
+// Stop waits for all queued metrics to be written and then stops the
+// processes.
 func (e *Execd) Stop() error {
-	e.process.Stop()
-	return nil
-}
-
-func (e *Execd) cmdReadOut(out io.Reader) {
-	scanner := bufio.NewScanner(out)
-
-	for scanner.Scan() {
-		metrics, err := e.parser.Parse(scanner.Bytes())
-		if err != nil {
-			e.Log.Errorf("Parse error: %s", err)
-		}
-
-		for _, metric := range metrics {
-			e.acc.AddMetric(metric)
-		}
+	for _, w := range e.workers {
+		close(w.queue)
 	}
-
-	if err := scanner.Err(); err != nil {
-		e.Log.Errorf("Error reading stdout: %s", err)
+	e.wg.Wait()
+	for _, w := range e.workers {
+		w.process.Stop()
 	}
+	return nil
 }
 
 func (e *Execd) cmdReadErr(out io.Reader) {
@@ -151,6 +155,17 @@ func (e *Execd) Init() error {
 	if len(e.Command) == 0 {
 		return errors.New("no command specified")
 	}
+	if e.Workers < 1 {
+		return errors.New("workers must be at least 1")
+	}
+	if e.WaitForResponse && e.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	switch e.TimeoutAction {
+	case "drop", "pass":
+	default:
+		return fmt.Errorf("invalid timeout_action %q", e.TimeoutAction)
+	}
 	return nil
 }
 
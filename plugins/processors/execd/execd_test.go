@@ -79,9 +79,147 @@ func TestExternalProcessorWorks(t *testing.T) {
 	}
 }
 
+func TestWorkersKeepSeriesOrder(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	e := New()
+	e.Log = testutil.Logger{}
+	e.Command = []string{exe, "-countmultiplier", "-response"}
+	e.Workers = 3
+	e.WaitForResponse = true
+	require.NoError(t, e.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+
+	cities := []string{"Toronto", "Montreal", "Vancouver", "Ottawa"}
+	now := time.Unix(0, 0)
+	for i := 0; i < 40; i++ {
+		m := testutil.MustMetric("test",
+			map[string]string{"city": cities[i%len(cities)]},
+			map[string]interface{}{"count": i},
+			now.Add(time.Duration(i)),
+		)
+		require.NoError(t, e.Add(m, acc))
+	}
+	require.NoError(t, e.Stop())
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 40)
+
+	last := make(map[string]int64)
+	for _, m := range metrics {
+		city, _ := m.GetTag("city")
+		ts := m.Time().UnixNano()
+		if prev, ok := last[city]; ok {
+			require.Greater(t, ts, prev)
+		}
+		last[city] = ts
+
+		count, _ := m.GetField("count")
+		require.Equal(t, 2*ts, count)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "drop",
+			action: "drop",
+			expected: []telegraf.Metric{
+				testutil.MustMetric("fast", map[string]string{}, map[string]interface{}{"count": 10}, time.Unix(0, 0)),
+			},
+		},
+		{
+			name:   "pass",
+			action: "pass",
+			expected: []telegraf.Metric{
+				testutil.MustMetric("slow", map[string]string{}, map[string]interface{}{"count": 1, "delay_ms": 1000}, time.Unix(0, 0)),
+				testutil.MustMetric("fast", map[string]string{}, map[string]interface{}{"count": 10}, time.Unix(0, 0)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exe, err := os.Executable()
+			require.NoError(t, err)
+
+			e := New()
+			e.Log = testutil.Logger{}
+			e.Command = []string{exe, "-countmultiplier", "-response"}
+			e.WaitForResponse = true
+			e.Timeout = config.Duration(600 * time.Millisecond)
+			e.TimeoutAction = tt.action
+			require.NoError(t, e.Init())
+
+			acc := &testutil.Accumulator{}
+			require.NoError(t, e.Start(acc))
+
+			// The late response of the slow metric must not be taken as
+			// the response of the fast one.
+			slow := testutil.MustMetric("slow", map[string]string{}, map[string]interface{}{"count": 1, "delay_ms": 1000}, time.Unix(0, 0))
+			fast := testutil.MustMetric("fast", map[string]string{}, map[string]interface{}{"count": 5}, time.Unix(0, 0))
+			require.NoError(t, e.Add(slow, acc))
+			require.NoError(t, e.Add(fast, acc))
+			require.NoError(t, e.Stop())
+
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics())
+		})
+	}
+}
+
+func TestTimeoutAfterRestart(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	e := New()
+	e.Log = testutil.Logger{}
+	e.Command = []string{exe, "-countmultiplier", "-response"}
+	e.WaitForResponse = true
+	e.Timeout = config.Duration(time.Second)
+	e.RestartDelay = config.Duration(100 * time.Millisecond)
+	require.NoError(t, e.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+
+	// The process exits without answering, the metric times out after the
+	// process was restarted.  The restarted process must still be answered.
+	crash := testutil.MustMetric("crash", map[string]string{}, map[string]interface{}{"count": 1, "exit": true}, time.Unix(0, 0))
+	fast := testutil.MustMetric("fast", map[string]string{}, map[string]interface{}{"count": 5}, time.Unix(0, 0))
+	require.NoError(t, e.Add(crash, acc))
+	require.NoError(t, e.Add(fast, acc))
+	require.NoError(t, e.Stop())
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("fast", map[string]string{}, map[string]interface{}{"count": 10}, time.Unix(0, 0)),
+	}, acc.GetTelegrafMetrics())
+}
+
+func TestInitErrors(t *testing.T) {
+	e := New()
+	require.Error(t, e.Init())
+
+	e.Command = []string{"cat"}
+	e.Workers = 0
+	require.Error(t, e.Init())
+
+	e.Workers = 1
+	e.TimeoutAction = "retry"
+	require.Error(t, e.Init())
+}
+
 var countmultiplier = flag.Bool("countmultiplier", false,
 	"if true, act like line input program instead of test")
 
+var response = flag.Bool("response", false,
+	"if true, write an empty line after each metric and sleep for delay_ms")
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	if *countmultiplier {
@@ -110,6 +248,14 @@ func runCountMultiplierProgram() {
 			os.Exit(1)
 		}
 
+		// Exit without output to test restarts.
+		if _, ok := metric.GetField("exit"); ok {
+			os.Exit(1)
+		}
+		if d, ok := metric.GetField("delay_ms"); ok {
+			time.Sleep(time.Duration(d.(int64)) * time.Millisecond)
+		}
+
 		c, found := metric.GetField("count")
 		if !found {
 			fmt.Fprintf(os.Stderr, "metric has no count field\n")
@@ -132,5 +278,8 @@ func runCountMultiplierProgram() {
 			os.Exit(1)
 		}
 		fmt.Fprint(os.Stdout, string(b))
+		if *response {
+			fmt.Fprint(os.Stdout, "\n")
+		}
 	}
 }
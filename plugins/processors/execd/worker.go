@@ -0,0 +1,224 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// worker writes the metrics of its queue to one process.
+type worker struct {
+	e          *Execd
+	process    *process.Process
+	parser     parsers.Parser
+	serializer serializers.Serializer
+	queue      chan telegraf.Metric
+
+	// responses receives the output of the current metric if waiting for
+	// responses.  skip is the number of responses of the running process to
+	// discard, belonging to metrics that timed out.  The running process is
+	// identified by its stdin, so that timeouts of metrics written to a
+	// process before it was restarted are not counted.
+	responses chan []telegraf.Metric
+	mu        sync.Mutex
+	skip      int
+	running   io.Writer
+}
+
+func (e *Execd) newWorker() (*worker, error) {
+	parser, err := parsers.NewParser(e.parserConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating parser: %w", err)
+	}
+	serializer, err := serializers.NewSerializer(e.serializerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating serializer: %w", err)
+	}
+
+	w := &worker{
+		e:          e,
+		parser:     parser,
+		serializer: serializer,
+		queue:      make(chan telegraf.Metric, queueSize),
+		responses:  make(chan []telegraf.Metric, 1),
+	}
+
+	w.process, err = process.New(e.Command)
+	if err != nil {
+		return nil, fmt.Errorf("error creating new process: %w", err)
+	}
+	w.process.Log = e.Log
+	w.process.RestartDelay = time.Duration(e.RestartDelay)
+	w.process.ReadStdoutFn = w.cmdReadOut
+	if e.WaitForResponse {
+		w.process.ReadStdoutFn = w.cmdReadResponses
+	}
+	w.process.ReadStderrFn = e.cmdReadErr
+	return w, nil
+}
+
+func (w *worker) start() error {
+	if err := w.process.Start(); err != nil {
+		// if there was only one argument, and it contained spaces, warn the user
+		// that they may have configured it wrong.
+		if len(w.e.Command) == 1 && strings.Contains(w.e.Command[0], " ") {
+			w.e.Log.Warn("The processors.execd Command contained spaces but no arguments. " +
+				"This setting expects the program and arguments as an array of strings, " +
+				"not as a space-delimited string. See the plugin readme for an example.")
+		}
+		return fmt.Errorf("failed to start process %s: %w", w.e.Command, err)
+	}
+	return nil
+}
+
+// run handles the queued metrics until the queue is closed.
+func (w *worker) run() {
+	for m := range w.queue {
+		stdin, err := w.write(m)
+		if err != nil {
+			w.e.Log.Error(err)
+			m.Drop()
+			w.e.inFlight.Incr(-1)
+			continue
+		}
+
+		if !w.e.WaitForResponse {
+			// We cannot maintain tracking metrics at the moment because input/output
+			// is done asynchronously and we don't have any metric metadata to tie the
+			// output metric back to the original input metric.
+			m.Drop()
+			w.e.inFlight.Incr(-1)
+			continue
+		}
+
+		w.waitForResponse(m, stdin)
+	}
+}
+
+// write writes the metric to the process and returns the stdin it was
+// written to.
+func (w *worker) write(m telegraf.Metric) (io.Writer, error) {
+	b, err := w.serializer.Serialize(m)
+	if err != nil {
+		return nil, fmt.Errorf("metric serializing error: %w", err)
+	}
+
+	stdin := w.process.Stdin
+	_, err = stdin.Write(b)
+	if err != nil {
+		return nil, fmt.Errorf("error writing to process stdin: %w", err)
+	}
+	return stdin, nil
+}
+
+// waitForResponse passes on the output of the metric written last to stdin,
+// or applies the timeout action if there is no output in time.
+func (w *worker) waitForResponse(m telegraf.Metric, stdin io.Writer) {
+	defer w.e.inFlight.Incr(-1)
+
+	start := time.Now()
+	timer := time.NewTimer(time.Duration(w.e.Timeout))
+	defer timer.Stop()
+
+	var metrics []telegraf.Metric
+	select {
+	case metrics = <-w.responses:
+	case <-timer.C:
+		// The response may arrive while the timeout is handled, check
+		// again while holding the lock of the reader.
+		w.mu.Lock()
+		select {
+		case metrics = <-w.responses:
+		default:
+			// A restarted process will not answer the metric.
+			if stdin == w.running {
+				w.skip++
+			}
+			w.mu.Unlock()
+
+			w.e.timeouts.Incr(1)
+			w.e.Log.Debugf("Timeout waiting for the response of metric %q", m.Name())
+			if w.e.TimeoutAction == "pass" {
+				w.e.acc.AddMetric(m)
+			} else {
+				m.Drop()
+			}
+			return
+		}
+		w.mu.Unlock()
+	}
+
+	w.e.responseTime.Incr(time.Since(start).Nanoseconds())
+	for _, metric := range metrics {
+		w.e.acc.AddMetric(metric)
+	}
+	m.Drop()
+}
+
+func (w *worker) cmdReadOut(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		metrics, err := w.parser.Parse(scanner.Bytes())
+		if err != nil {
+			w.e.Log.Errorf("Parse error: %s", err)
+		}
+
+		for _, metric := range metrics {
+			w.e.acc.AddMetric(metric)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		w.e.Log.Errorf("Error reading stdout: %s", err)
+	}
+}
+
+// cmdReadResponses reads the output of the process, an empty line ends the
+// output of a metric.
+func (w *worker) cmdReadResponses(out io.Reader) {
+	// A restarted process will not answer the metrics sent before.
+	w.mu.Lock()
+	w.running = w.process.Stdin
+	w.skip = 0
+	w.mu.Unlock()
+
+	scanner := bufio.NewScanner(out)
+
+	var response []telegraf.Metric
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			w.mu.Lock()
+			if w.skip > 0 {
+				w.skip--
+			} else {
+				select {
+				case w.responses <- response:
+				default:
+					w.e.Log.Errorf("Received response without metric, discarding %d metrics", len(response))
+				}
+			}
+			w.mu.Unlock()
+			response = nil
+			continue
+		}
+
+		metrics, err := w.parser.Parse(scanner.Bytes())
+		if err != nil {
+			w.e.Log.Errorf("Parse error: %s", err)
+		}
+		response = append(response, metrics...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		w.e.Log.Errorf("Error reading stdout: %s", err)
+	}
+}
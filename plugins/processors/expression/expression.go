@@ -0,0 +1,188 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Knetic/govaluate"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Each rule sets a field to the result of an arithmetic expression over
+  ## the fields and tags of the metric, see the README for the syntax and
+  ## functions.  Rules are evaluated in order, so later rules can use the
+  ## fields set by earlier ones.  A rule is skipped if a field or tag used by
+  ## the expression is missing.
+  [[processors.expression.rule]]
+    ## Field to create or replace.
+    field = "used_percent"
+
+    ## Expression to evaluate.
+    expression = "used / total * 100"
+
+    ## Type of the field, one of "float", "integer", "unsigned", "boolean"
+    ## or "string".
+    # type = "float"
+`
+
+type Rule struct {
+	Field      string `toml:"field"`
+	Expression string `toml:"expression"`
+	Type       string `toml:"type"`
+
+	expr *govaluate.EvaluableExpression
+	vars []string
+}
+
+type Expression struct {
+	Rules []Rule `toml:"rule"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+func (e *Expression) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Expression) Description() string {
+	return "Compute fields from arithmetic expressions over fields and tags"
+}
+
+func (e *Expression) Init() error {
+	for i := range e.Rules {
+		if err := e.Rules[i].init(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) init() error {
+	if r.Field == "" {
+		return errors.New("no field set")
+	}
+	switch r.Type {
+	case "":
+		r.Type = "float"
+	case "float", "integer", "unsigned", "boolean", "string":
+	default:
+		return fmt.Errorf("invalid type %q", r.Type)
+	}
+
+	var err error
+	r.expr, err = govaluate.NewEvaluableExpressionWithFunctions(r.Expression, functions)
+	if err != nil {
+		return fmt.Errorf("invalid expression %q: %v", r.Expression, err)
+	}
+	r.vars = r.expr.Vars()
+	return nil
+}
+
+func (e *Expression) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		// Fields take precedence over tags of the same name.
+		params := make(map[string]interface{}, len(metric.TagList())+len(metric.FieldList()))
+		for _, tag := range metric.TagList() {
+			params[tag.Key] = tag.Value
+		}
+		for _, field := range metric.FieldList() {
+			params[field.Key] = field.Value
+		}
+
+		for i := range e.Rules {
+			r := &e.Rules[i]
+			if !hasVars(params, r.vars) {
+				continue
+			}
+
+			result, err := r.expr.Evaluate(params)
+			if err != nil {
+				e.Log.Debugf("Evaluating %q for field %q failed: %v", r.Expression, r.Field, err)
+				continue
+			}
+
+			value, ok := convert(result, r.Type)
+			if !ok {
+				e.Log.Debugf("Result %v of %q can not be stored as %s", result, r.Expression, r.Type)
+				continue
+			}
+			metric.AddField(r.Field, value)
+			params[r.Field] = value
+		}
+	}
+	return in
+}
+
+func hasVars(params map[string]interface{}, vars []string) bool {
+	for _, v := range vars {
+		if _, ok := params[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// convert returns the result of an expression as a field value of the type,
+// numbers are rounded to the nearest integer for the integer types.
+func convert(v interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "float":
+		switch v := v.(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, false
+			}
+			return v, true
+		case bool:
+			if v {
+				return 1.0, true
+			}
+			return 0.0, true
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+		}
+	case "integer":
+		f, ok := convert(v, "float")
+		if !ok || f.(float64) < math.MinInt64 || f.(float64) > math.MaxInt64 {
+			return nil, false
+		}
+		return int64(math.Round(f.(float64))), true
+	case "unsigned":
+		f, ok := convert(v, "float")
+		if !ok || f.(float64) < 0 || f.(float64) > math.MaxUint64 {
+			return nil, false
+		}
+		return uint64(math.Round(f.(float64))), true
+	case "boolean":
+		switch v := v.(type) {
+		case bool:
+			return v, true
+		case float64:
+			return v != 0, true
+		case string:
+			b, err := strconv.ParseBool(v)
+			return b, err == nil
+		}
+	case "string":
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("expression", func() telegraf.Processor {
+		return &Expression{}
+	})
+}
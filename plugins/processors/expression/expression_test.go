@@ -0,0 +1,138 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func apply(t *testing.T, rules []Rule, m telegraf.Metric) telegraf.Metric {
+	e := &Expression{Rules: rules, Log: testutil.Logger{}}
+	require.NoError(t, e.Init())
+	out := e.Apply(m)
+	require.Len(t, out, 1)
+	return out[0]
+}
+
+func TestArithmetic(t *testing.T) {
+	m := testutil.MustMetric("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": int64(25), "total": uint64(200)},
+		time.Unix(0, 0),
+	)
+	actual := apply(t, []Rule{
+		{Field: "used_percent", Expression: "used / total * 100"},
+		{Field: "free", Expression: "total - used", Type: "integer"},
+		// Uses the field created by the previous rule.
+		{Field: "free_percent", Expression: "free / total * 100"},
+	}, m)
+
+	expected := testutil.MustMetric("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{
+			"used":         int64(25),
+			"total":        uint64(200),
+			"used_percent": 12.5,
+			"free":         int64(175),
+			"free_percent": 87.5,
+		},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestFunctions(t *testing.T) {
+	m := testutil.MustMetric("cache",
+		map[string]string{"role": "primary"},
+		map[string]interface{}{"hits": 30, "misses": 10, "delta": -2.5},
+		time.Unix(0, 0),
+	)
+	actual := apply(t, []Rule{
+		{Field: "hit_ratio", Expression: "round(hits / (hits + misses) * 100)"},
+		{Field: "abs_delta", Expression: "abs(delta)"},
+		{Field: "largest", Expression: "max(hits, misses, 12)"},
+		{Field: "log", Expression: "log10(100)"},
+		{Field: "state", Expression: "if(misses > 20, 'bad', 'good')", Type: "string"},
+		{Field: "is_primary", Expression: "role == 'primary'", Type: "boolean"},
+		{Field: "level", Expression: "hits > 20 ? 2 : 1", Type: "unsigned"},
+	}, m)
+
+	expected := testutil.MustMetric("cache",
+		map[string]string{"role": "primary"},
+		map[string]interface{}{
+			"hits":       30,
+			"misses":     10,
+			"delta":      -2.5,
+			"hit_ratio":  75.0,
+			"abs_delta":  2.5,
+			"largest":    30.0,
+			"log":        2.0,
+			"state":      "good",
+			"is_primary": true,
+			"level":      uint64(2),
+		},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestSkip(t *testing.T) {
+	m := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"used": 0, "total": 0},
+		time.Unix(0, 0),
+	)
+	actual := apply(t, []Rule{
+		// Missing operand.
+		{Field: "a", Expression: "used / free"},
+		// Not a finite number.
+		{Field: "b", Expression: "used / total"},
+		// Not a number.
+		{Field: "c", Expression: "'x' + used"},
+		// Replaces the existing field.
+		{Field: "used", Expression: "total + 1", Type: "integer"},
+	}, m)
+
+	expected := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"used": 1, "total": 0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestBracketVariables(t *testing.T) {
+	m := testutil.MustMetric("zfs",
+		map[string]string{},
+		map[string]interface{}{"arcstats.hits": 9, "arcstats.misses": 1},
+		time.Unix(0, 0),
+	)
+	actual := apply(t, []Rule{
+		{Field: "arc_hit_ratio", Expression: "[arcstats.hits] / ([arcstats.hits] + [arcstats.misses])"},
+	}, m)
+
+	v, ok := actual.GetField("arc_hit_ratio")
+	require.True(t, ok)
+	require.Equal(t, 0.9, v)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "no field", rule: Rule{Expression: "1 + 1"}},
+		{name: "invalid type", rule: Rule{Field: "a", Expression: "1", Type: "time"}},
+		{name: "invalid expression", rule: Rule{Field: "a", Expression: "1 +"}},
+		{name: "unknown function", rule: Rule{Field: "a", Expression: "foo(1)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Expression{Rules: []Rule{tt.rule}}
+			require.Error(t, e.Init())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Knetic/govaluate"
+)
+
+// functions are the functions available in expressions.
+var functions = map[string]govaluate.ExpressionFunction{
+	"abs":   unary("abs", math.Abs),
+	"ceil":  unary("ceil", math.Ceil),
+	"floor": unary("floor", math.Floor),
+	"round": unary("round", math.Round),
+	"sqrt":  unary("sqrt", math.Sqrt),
+	"exp":   unary("exp", math.Exp),
+	"log":   unary("log", math.Log),
+	"log2":  unary("log2", math.Log2),
+	"log10": unary("log10", math.Log10),
+	"pow": func(args ...interface{}) (interface{}, error) {
+		x, err := floatArgs("pow", 2, args)
+		if err != nil {
+			return nil, err
+		}
+		return math.Pow(x[0], x[1]), nil
+	},
+	"min": func(args ...interface{}) (interface{}, error) {
+		x, err := floatArgs("min", -1, args)
+		if err != nil {
+			return nil, err
+		}
+		result := x[0]
+		for _, v := range x[1:] {
+			result = math.Min(result, v)
+		}
+		return result, nil
+	},
+	"max": func(args ...interface{}) (interface{}, error) {
+		x, err := floatArgs("max", -1, args)
+		if err != nil {
+			return nil, err
+		}
+		result := x[0]
+		for _, v := range x[1:] {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	},
+	"if": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("if expects 3 arguments, got %d", len(args))
+		}
+		cond, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("if expects a boolean condition, got %T", args[0])
+		}
+		if cond {
+			return args[1], nil
+		}
+		return args[2], nil
+	},
+}
+
+func unary(name string, fn func(float64) float64) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		x, err := floatArgs(name, 1, args)
+		if err != nil {
+			return nil, err
+		}
+		return fn(x[0]), nil
+	}
+}
+
+// floatArgs checks the number of arguments, -1 for at least one, and
+// converts them to floats.
+func floatArgs(name string, n int, args []interface{}) ([]float64, error) {
+	if (n < 0 && len(args) == 0) || (n >= 0 && len(args) != n) {
+		return nil, fmt.Errorf("%s: wrong number of arguments %d", name, len(args))
+	}
+	x := make([]float64, 0, len(args))
+	for _, arg := range args {
+		v, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s: expects numbers, got %T", name, arg)
+		}
+		x = append(x, v)
+	}
+	return x, nil
+}
@@ -0,0 +1,183 @@
+package lookup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## List of files containing the lookup table, entries of later files
+  ## replace entries of earlier files with the same key.
+  files = ["/etc/telegraf/hosts.csv"]
+
+  ## Format of the files, either "csv" or "json".
+  ##
+  ## A csv file starts with a header row, the first column holds the key and
+  ## the other columns are the tags to add, named by the header.  Empty
+  ## values are not added.
+  ##
+  ## A json file holds an object mapping each key to an object of tags.
+  # format = "csv"
+
+  ## Tags used to build the key, the values are joined with the key
+  ## separator.  Metrics missing any of the tags are not modified.
+  key_tags = ["host"]
+  # key_separator = ":"
+
+  ## Replace existing tags with the tags from the table.
+  # overwrite = false
+
+  ## Interval at which the files are checked for changes and reloaded, set
+  ## to "0s" to disable reloading.
+  # reload_interval = "1m"
+`
+
+type Lookup struct {
+	Files          []string          `toml:"files"`
+	Format         string            `toml:"format"`
+	KeyTags        []string          `toml:"key_tags"`
+	KeySeparator   string            `toml:"key_separator"`
+	Overwrite      bool              `toml:"overwrite"`
+	ReloadInterval internal.Duration `toml:"reload_interval"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	table     map[string]map[string]string
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+	parse     func(path string) (map[string]map[string]string, error)
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Add tags to metrics from a lookup table"
+}
+
+func (l *Lookup) Init() error {
+	if len(l.Files) == 0 {
+		return fmt.Errorf("no files set")
+	}
+	if len(l.KeyTags) == 0 {
+		return fmt.Errorf("no key_tags set")
+	}
+
+	switch l.Format {
+	case "", "csv":
+		l.parse = parseCSV
+	case "json":
+		l.parse = parseJSON
+	default:
+		return fmt.Errorf("invalid format %q", l.Format)
+	}
+
+	return l.load()
+}
+
+func (l *Lookup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	l.reload()
+
+	for _, metric := range in {
+		key, ok := l.key(metric)
+		if !ok {
+			continue
+		}
+		for k, v := range l.table[key] {
+			if !l.Overwrite && metric.HasTag(k) {
+				continue
+			}
+			metric.AddTag(k, v)
+		}
+	}
+	return in
+}
+
+// key builds the lookup key from the key tags.
+func (l *Lookup) key(metric telegraf.Metric) (string, bool) {
+	if len(l.KeyTags) == 1 {
+		return metric.GetTag(l.KeyTags[0])
+	}
+
+	values := make([]string, 0, len(l.KeyTags))
+	for _, tag := range l.KeyTags {
+		v, ok := metric.GetTag(tag)
+		if !ok {
+			return "", false
+		}
+		values = append(values, v)
+	}
+	return strings.Join(values, l.KeySeparator), true
+}
+
+// load reads all files into a new table.
+func (l *Lookup) load() error {
+	table := make(map[string]map[string]string)
+	modTimes := make(map[string]time.Time, len(l.Files))
+	for _, path := range l.Files {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = stat.ModTime()
+
+		entries, err := l.parse(path)
+		if err != nil {
+			return fmt.Errorf("loading %q: %v", path, err)
+		}
+		for k, tags := range entries {
+			table[k] = tags
+		}
+	}
+
+	l.table = table
+	l.modTimes = modTimes
+	l.lastCheck = time.Now()
+	return nil
+}
+
+// reload loads the files again if the reload interval has passed and any of
+// them was modified.  On errors the previous table is kept.
+func (l *Lookup) reload() {
+	if l.ReloadInterval.Duration <= 0 || time.Since(l.lastCheck) < l.ReloadInterval.Duration {
+		return
+	}
+	l.lastCheck = time.Now()
+
+	modified := false
+	for _, path := range l.Files {
+		stat, err := os.Stat(path)
+		if err != nil {
+			l.Log.Errorf("Checking %q failed: %v", path, err)
+			return
+		}
+		if !stat.ModTime().Equal(l.modTimes[path]) {
+			modified = true
+		}
+	}
+	if !modified {
+		return
+	}
+
+	if err := l.load(); err != nil {
+		l.Log.Errorf("Reloading lookup table failed, keeping the previous table: %v", err)
+		return
+	}
+	l.Log.Debugf("Reloaded lookup table with %d entries", len(l.table))
+}
+
+func init() {
+	processors.Add("lookup", func() telegraf.Processor {
+		return &Lookup{
+			KeySeparator:   ":",
+			ReloadInterval: internal.Duration{Duration: time.Minute},
+		}
+	})
+}
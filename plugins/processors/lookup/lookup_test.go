@@ -0,0 +1,154 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric("net",
+		tags,
+		map[string]interface{}{"bytes": 42},
+		time.Unix(0, 0),
+	)
+}
+
+func TestCompositeKeyCSV(t *testing.T) {
+	l := &Lookup{
+		Files:        []string{"testdata/hosts.csv"},
+		KeyTags:      []string{"host", "interface"},
+		KeySeparator: ":",
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, l.Init())
+
+	out := l.Apply(
+		newMetric(map[string]string{"host": "server01", "interface": "eth0"}),
+		newMetric(map[string]string{"host": "server02", "interface": "eth0"}),
+		newMetric(map[string]string{"host": "server02", "interface": "eth1"}),
+		newMetric(map[string]string{"host": "server01"}),
+	)
+
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"host": "server01", "interface": "eth0", "owner": "alice", "team": "storage", "datacenter": "fra1"}),
+		newMetric(map[string]string{"host": "server02", "interface": "eth0", "owner": "bob", "datacenter": "ams3"}),
+		newMetric(map[string]string{"host": "server02", "interface": "eth1"}),
+		newMetric(map[string]string{"host": "server01"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestJSONOverwrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		expected  telegraf.Metric
+	}{
+		{
+			name:     "keep existing",
+			expected: newMetric(map[string]string{"host": "server01", "owner": "dave", "team": "storage"}),
+		},
+		{
+			name:      "overwrite",
+			overwrite: true,
+			expected:  newMetric(map[string]string{"host": "server01", "owner": "alice", "team": "storage"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Lookup{
+				Files:     []string{"testdata/hosts.json"},
+				Format:    "json",
+				KeyTags:   []string{"host"},
+				Overwrite: tt.overwrite,
+				Log:       testutil.Logger{},
+			}
+			require.NoError(t, l.Init())
+
+			out := l.Apply(newMetric(map[string]string{"host": "server01", "owner": "dave"}))
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{tt.expected}, out)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte("host,team\nserver01,storage\n"), 0644))
+
+	l := &Lookup{
+		Files:          []string{path},
+		KeyTags:        []string{"host"},
+		ReloadInterval: internal.Duration{Duration: time.Nanosecond},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, l.Init())
+
+	out := l.Apply(newMetric(map[string]string{"host": "server01"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "server01", "team": "storage"}),
+	}, out)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("host,team\nserver01,network\n"), 0644))
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, future, future))
+
+	out = l.Apply(newMetric(map[string]string{"host": "server01"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "server01", "team": "network"}),
+	}, out)
+
+	// A broken file keeps the previous table.
+	require.NoError(t, ioutil.WriteFile(path, []byte("host,team\nserver01\n"), 0644))
+	future = future.Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, future, future))
+
+	out = l.Apply(newMetric(map[string]string{"host": "server01"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "server01", "team": "network"}),
+	}, out)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Lookup
+	}{
+		{
+			name:   "no files",
+			plugin: &Lookup{KeyTags: []string{"host"}},
+		},
+		{
+			name:   "no key tags",
+			plugin: &Lookup{Files: []string{"testdata/hosts.csv"}},
+		},
+		{
+			name:   "invalid format",
+			plugin: &Lookup{Files: []string{"testdata/hosts.csv"}, KeyTags: []string{"host"}, Format: "yaml"},
+		},
+		{
+			name:   "missing file",
+			plugin: &Lookup{Files: []string{"testdata/missing.csv"}, KeyTags: []string{"host"}},
+		},
+		{
+			name:   "invalid json",
+			plugin: &Lookup{Files: []string{"testdata/hosts.csv"}, KeyTags: []string{"host"}, Format: "json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
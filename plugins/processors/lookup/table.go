@@ -0,0 +1,62 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// parseCSV reads a table with a header row, the first column is the key and
+// the other columns are tags.
+func parseCSV(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	header := records[0]
+	if len(header) < 2 {
+		return nil, errors.New("header must contain the key and at least one tag")
+	}
+
+	table := make(map[string]map[string]string, len(records)-1)
+	for _, record := range records[1:] {
+		tags := make(map[string]string, len(header)-1)
+		for i, name := range header[1:] {
+			if v := record[i+1]; v != "" {
+				tags[name] = v
+			}
+		}
+		table[record[0]] = tags
+	}
+	return table, nil
+}
+
+// parseJSON reads an object mapping each key to an object of tags.
+func parseJSON(path string) (map[string]map[string]string, error) {
+	octets, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var table map[string]map[string]string
+	if err := json.Unmarshal(octets, &table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
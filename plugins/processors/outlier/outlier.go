@@ -0,0 +1,284 @@
+package outlier
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const (
+	methodMedian = "median"
+	methodEWMA   = "ewma"
+
+	actionNone  = "none"
+	actionTag   = "tag"
+	actionClamp = "clamp"
+	actionDrop  = "drop"
+)
+
+var sampleConfig = `
+  ## Fields to check, glob patterns are supported.
+  fields = ["*"]
+
+  ## Statistic used to find outliers, either "median" for the rolling median
+  ## and median absolute deviation of the last window values, or "ewma" for
+  ## the exponentially weighted moving average and standard deviation.
+  # method = "median"
+
+  ## Number of values in the rolling window of the median method.
+  # window = 20
+
+  ## Weight of the newest value for the ewma method, between 0 and 1.
+  # alpha = 0.3
+
+  ## Number of values of a series required before it is checked.
+  # min_samples = 5
+
+  ## A value is an outlier if it is more than this number of deviations
+  ## away from the expected value.
+  # threshold = 3.0
+
+  ## Smallest deviation used, in the unit of the field.  Without it any
+  ## change of a series that was constant so far is an outlier; set it to
+  ## the resolution of the values, such as 1 for integer gauges, to allow
+  ## small changes.
+  # min_deviation = 0.0
+
+  ## Action for outliers, one of:
+  ##   "none"  - only add the score field
+  ##   "tag"   - add the tag set by "tag_key" with the value "true"
+  ##   "clamp" - replace the value by the closest value within the threshold
+  ##   "drop"  - remove the field, metrics without fields are dropped
+  # action = "tag"
+  # tag_key = "outlier"
+
+  ## If set, a field with this suffix and the anomaly score, the distance
+  ## to the expected value in deviations, is added for every checked field.
+  # score_suffix = "_score"
+
+  ## Series that are not updated within this time are removed.
+  # series_timeout = "1h"
+`
+
+type Outlier struct {
+	Fields        []string          `toml:"fields"`
+	Method        string            `toml:"method"`
+	Window        int               `toml:"window"`
+	Alpha         float64           `toml:"alpha"`
+	MinSamples    int               `toml:"min_samples"`
+	Threshold     float64           `toml:"threshold"`
+	MinDeviation  float64           `toml:"min_deviation"`
+	Action        string            `toml:"action"`
+	TagKey        string            `toml:"tag_key"`
+	ScoreSuffix   string            `toml:"score_suffix"`
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+
+	fieldFilter  filter.Filter
+	newEstimator func() estimator
+	cache        map[uint64]*series
+	lastCleanup  time.Time
+}
+
+type series struct {
+	fields   map[string]estimator
+	lastSeen time.Time
+}
+
+func (o *Outlier) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *Outlier) Description() string {
+	return "Find outliers using rolling statistics per series"
+}
+
+func (o *Outlier) Init() error {
+	if o.MinSamples < 2 {
+		return fmt.Errorf("min_samples must be at least 2")
+	}
+	if o.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if o.MinDeviation < 0 {
+		return fmt.Errorf("min_deviation must not be negative")
+	}
+
+	switch o.Method {
+	case "", methodMedian:
+		if o.Window < o.MinSamples {
+			return fmt.Errorf("window must not be smaller than min_samples")
+		}
+		o.newEstimator = func() estimator { return newMedian(o.Window, o.MinSamples) }
+	case methodEWMA:
+		if o.Alpha <= 0 || o.Alpha > 1 {
+			return fmt.Errorf("alpha must be in the range (0,1]")
+		}
+		o.newEstimator = func() estimator { return &ewma{alpha: o.Alpha, minSamples: o.MinSamples} }
+	default:
+		return fmt.Errorf("invalid method %q", o.Method)
+	}
+
+	switch o.Action {
+	case actionNone, actionTag, actionClamp, actionDrop:
+	default:
+		return fmt.Errorf("invalid action %q", o.Action)
+	}
+
+	fields := o.Fields
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	var err error
+	o.fieldFilter, err = filter.Compile(fields)
+	if err != nil {
+		return fmt.Errorf("compiling fields: %v", err)
+	}
+
+	o.cache = make(map[uint64]*series)
+	o.lastCleanup = time.Now()
+	return nil
+}
+
+func (o *Outlier) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if o.apply(metric) {
+			out = append(out, metric)
+		} else {
+			metric.Drop()
+		}
+	}
+	o.cleanup()
+	return out
+}
+
+// apply checks the fields of the metric and reports if it should be kept.
+func (o *Outlier) apply(metric telegraf.Metric) bool {
+	id := metric.HashID()
+	s, ok := o.cache[id]
+	if !ok {
+		s = &series{fields: make(map[string]estimator)}
+		o.cache[id] = s
+	}
+	s.lastSeen = time.Now()
+
+	// The field list is modified while iterating, so work on a copy.
+	fields := make([]telegraf.Field, 0, len(metric.FieldList()))
+	for _, field := range metric.FieldList() {
+		if o.fieldFilter.Match(field.Key) {
+			fields = append(fields, *field)
+		}
+	}
+
+	for _, field := range fields {
+		v, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+
+		est, ok := s.fields[field.Key]
+		if !ok {
+			est = o.newEstimator()
+			s.fields[field.Key] = est
+		}
+
+		center, deviation, ok := est.estimate()
+		if !ok {
+			est.add(v)
+			continue
+		}
+
+		deviation = math.Max(deviation, o.MinDeviation)
+		score := 0.0
+		if deviation > 0 {
+			score = math.Abs(v-center) / deviation
+		} else if v != center {
+			// Any change of a series without variation is an outlier.
+			score = math.Inf(1)
+		}
+		if o.ScoreSuffix != "" && !math.IsInf(score, 0) {
+			metric.AddField(field.Key+o.ScoreSuffix, score)
+		}
+
+		if score <= o.Threshold {
+			est.add(v)
+			continue
+		}
+
+		// Only a clamped value is added to the statistics, so a single
+		// spike does not move the expected value.
+		limit := center + math.Copysign(o.Threshold*deviation, v-center)
+		est.add(limit)
+
+		switch o.Action {
+		case actionTag:
+			metric.AddTag(o.TagKey, "true")
+		case actionClamp:
+			metric.AddField(field.Key, sameType(field.Value, limit))
+		case actionDrop:
+			metric.RemoveField(field.Key)
+		}
+	}
+	return len(metric.FieldList()) > 0
+}
+
+// cleanup removes series that have not been updated within the timeout.
+func (o *Outlier) cleanup() {
+	if o.SeriesTimeout.Duration <= 0 || time.Since(o.lastCleanup) < o.SeriesTimeout.Duration {
+		return
+	}
+	o.lastCleanup = time.Now()
+	for id, s := range o.cache {
+		if time.Since(s.lastSeen) > o.SeriesTimeout.Duration {
+			delete(o.cache, id)
+		}
+	}
+}
+
+// sameType converts v to the type of the field value.
+func sameType(value interface{}, v float64) interface{} {
+	switch value.(type) {
+	case int64:
+		return int64(math.Round(v))
+	case uint64:
+		if v < 0 {
+			return uint64(0)
+		}
+		return uint64(math.Round(v))
+	default:
+		return v
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("outlier", func() telegraf.Processor {
+		return &Outlier{
+			Method:        methodMedian,
+			Window:        20,
+			Alpha:         0.3,
+			MinSamples:    5,
+			Threshold:     3.0,
+			Action:        actionTag,
+			TagKey:        "outlier",
+			SeriesTimeout: internal.Duration{Duration: time.Hour},
+		}
+	})
+}
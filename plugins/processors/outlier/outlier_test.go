@@ -0,0 +1,234 @@
+package outlier
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newOutlier() *Outlier {
+	return &Outlier{
+		Window:     10,
+		Alpha:      0.3,
+		MinSamples: 5,
+		Threshold:  3.0,
+		Action:     "tag",
+		TagKey:     "outlier",
+	}
+}
+
+func newMetric(v float64) telegraf.Metric {
+	return testutil.MustMetric("sensors",
+		map[string]string{"chip": "coretemp"},
+		map[string]interface{}{"temp": v},
+		time.Unix(0, 0),
+	)
+}
+
+// warmup adds values alternating around 50 with a deviation of 1.
+func warmup(t *testing.T, o *Outlier) {
+	for i := 0; i < 10; i++ {
+		out := o.Apply(newMetric(50 + float64(i%3) - 1))
+		require.Len(t, out, 1)
+		require.False(t, out[0].HasTag("outlier"))
+	}
+}
+
+func TestTag(t *testing.T) {
+	for _, method := range []string{"median", "ewma"} {
+		t.Run(method, func(t *testing.T) {
+			o := newOutlier()
+			o.Method = method
+			require.NoError(t, o.Init())
+			warmup(t, o)
+
+			out := o.Apply(newMetric(90), newMetric(50.5))
+			require.Len(t, out, 2)
+			require.True(t, out[0].HasTag("outlier"))
+			require.False(t, out[1].HasTag("outlier"))
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	o := newOutlier()
+	o.Action = "clamp"
+	require.NoError(t, o.Init())
+	warmup(t, o)
+
+	// The median is 50 and the deviation 1.4826, the upper limit is three
+	// times the deviation above the median.
+	out := o.Apply(newMetric(90))
+	require.Len(t, out, 1)
+	v, ok := out[0].GetField("temp")
+	require.True(t, ok)
+	require.InDelta(t, 50+3*madScale, v, 1e-9)
+}
+
+func TestDrop(t *testing.T) {
+	o := newOutlier()
+	o.Action = "drop"
+	require.NoError(t, o.Init())
+	warmup(t, o)
+
+	out := o.Apply(newMetric(10))
+	require.Empty(t, out)
+
+	m := testutil.MustMetric("sensors",
+		map[string]string{"chip": "coretemp"},
+		map[string]interface{}{"temp": 10.0, "label": "core0"},
+		time.Unix(0, 0),
+	)
+	out = o.Apply(m)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensors",
+			map[string]string{"chip": "coretemp"},
+			map[string]interface{}{"label": "core0"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestScore(t *testing.T) {
+	o := newOutlier()
+	o.Action = "none"
+	o.ScoreSuffix = "_score"
+	require.NoError(t, o.Init())
+
+	// No score is added during warmup.
+	for i := 0; i < 5; i++ {
+		out := o.Apply(newMetric(50 + float64(i%3) - 1))
+		require.False(t, out[0].HasField("temp_score"))
+	}
+
+	out := o.Apply(newMetric(50 + 2*madScale))
+	score, ok := out[0].GetField("temp_score")
+	require.True(t, ok)
+	require.InDelta(t, 2.0, score, 1e-9)
+	require.False(t, out[0].HasTag("outlier"))
+}
+
+func TestConstantSeries(t *testing.T) {
+	for _, method := range []string{"median", "ewma"} {
+		t.Run(method, func(t *testing.T) {
+			o := newOutlier()
+			o.Method = method
+			require.NoError(t, o.Init())
+			for i := 0; i < 5; i++ {
+				out := o.Apply(newMetric(20))
+				require.False(t, out[0].HasTag("outlier"))
+			}
+
+			out := o.Apply(newMetric(20), newMetric(1000), newMetric(20))
+			require.Len(t, out, 3)
+			require.False(t, out[0].HasTag("outlier"))
+			require.True(t, out[1].HasTag("outlier"))
+			require.False(t, out[2].HasTag("outlier"))
+		})
+	}
+}
+
+func newIntMetric(v int64) telegraf.Metric {
+	return testutil.MustMetric("sensors",
+		map[string]string{"chip": "coretemp"},
+		map[string]interface{}{"temp": v},
+		time.Unix(0, 0),
+	)
+}
+
+func TestIntegerSeries(t *testing.T) {
+	o := newOutlier()
+	o.Action = "clamp"
+	require.NoError(t, o.Init())
+
+	// The median absolute deviation is 0, the standard deviation is used.
+	for _, v := range []int64{5, 5, 5, 6, 5, 5, 4, 5, 5, 5} {
+		o.Apply(newIntMetric(v))
+	}
+	out := o.Apply(newIntMetric(6))
+	require.Equal(t, int64(6), out[0].Fields()["temp"])
+
+	// Clamped values keep the type of the field, the limit 5 + 3 * 0.548 is
+	// rounded.
+	out = o.Apply(newIntMetric(20))
+	require.Equal(t, int64(7), out[0].Fields()["temp"])
+}
+
+func TestMinDeviation(t *testing.T) {
+	o := newOutlier()
+	o.MinDeviation = 1
+	o.ScoreSuffix = "_score"
+	require.NoError(t, o.Init())
+	for i := 0; i < 5; i++ {
+		o.Apply(newIntMetric(5))
+	}
+
+	out := o.Apply(newIntMetric(6), newIntMetric(20))
+	require.False(t, out[0].HasTag("outlier"))
+	require.Equal(t, 1.0, out[0].Fields()["temp_score"])
+	require.True(t, out[1].HasTag("outlier"))
+	require.Equal(t, 15.0, out[1].Fields()["temp_score"])
+}
+
+func TestSeriesAreSeparate(t *testing.T) {
+	o := newOutlier()
+	require.NoError(t, o.Init())
+	warmup(t, o)
+
+	// A different series has no statistics yet.
+	m := testutil.MustMetric("sensors",
+		map[string]string{"chip": "acpitz"},
+		map[string]interface{}{"temp": 90.0},
+		time.Unix(0, 0),
+	)
+	out := o.Apply(m)
+	require.False(t, out[0].HasTag("outlier"))
+}
+
+func TestEstimators(t *testing.T) {
+	m := newMedian(5, 3)
+	for _, v := range []float64{100, 1, 2, 3, 4, 5} {
+		m.add(v)
+	}
+	// The oldest value fell out of the window.
+	center, deviation, ok := m.estimate()
+	require.True(t, ok)
+	require.Equal(t, 3.0, center)
+	require.Equal(t, madScale, deviation)
+
+	e := &ewma{alpha: 0.5, minSamples: 2}
+	e.add(10)
+	_, _, ok = e.estimate()
+	require.False(t, ok)
+	e.add(20)
+	center, deviation, ok = e.estimate()
+	require.True(t, ok)
+	require.Equal(t, 15.0, center)
+	require.Equal(t, math.Sqrt(25), deviation)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *Outlier)
+	}{
+		{"invalid method", func(o *Outlier) { o.Method = "mean" }},
+		{"invalid action", func(o *Outlier) { o.Action = "alert" }},
+		{"window too small", func(o *Outlier) { o.Window = 2 }},
+		{"alpha out of range", func(o *Outlier) { o.Method = "ewma"; o.Alpha = 1.5 }},
+		{"threshold not positive", func(o *Outlier) { o.Threshold = 0 }},
+		{"too few samples", func(o *Outlier) { o.MinSamples = 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOutlier()
+			tt.modify(o)
+			require.Error(t, o.Init())
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package outlier
+
+import (
+	"math"
+	"sort"
+)
+
+// madScale makes the median absolute deviation a consistent estimator of
+// the standard deviation for normally distributed values.
+const madScale = 1.4826
+
+// estimator keeps the rolling statistics of one field.
+type estimator interface {
+	// estimate returns the expected value and the deviation, ok is false
+	// until enough samples have been added.
+	estimate() (center, deviation float64, ok bool)
+	add(v float64)
+}
+
+// ewma is an exponentially weighted moving average and variance.
+type ewma struct {
+	alpha      float64
+	minSamples int
+
+	count    int
+	mean     float64
+	variance float64
+}
+
+func (e *ewma) estimate() (float64, float64, bool) {
+	if e.count < e.minSamples {
+		return 0, 0, false
+	}
+	return e.mean, math.Sqrt(e.variance), true
+}
+
+func (e *ewma) add(v float64) {
+	e.count++
+	if e.count == 1 {
+		e.mean = v
+		return
+	}
+	diff := v - e.mean
+	incr := e.alpha * diff
+	e.mean += incr
+	e.variance = (1 - e.alpha) * (e.variance + diff*incr)
+}
+
+// median is the rolling median and median absolute deviation of the last
+// values.
+type median struct {
+	minSamples int
+
+	values []float64
+	next   int
+}
+
+func newMedian(window, minSamples int) *median {
+	return &median{
+		minSamples: minSamples,
+		values:     make([]float64, 0, window),
+	}
+}
+
+func (m *median) estimate() (float64, float64, bool) {
+	if len(m.values) < m.minSamples {
+		return 0, 0, false
+	}
+
+	sorted := append([]float64(nil), m.values...)
+	sort.Float64s(sorted)
+	center := middle(sorted)
+
+	var squares float64
+	for i, v := range sorted {
+		sorted[i] = math.Abs(v - center)
+		squares += sorted[i] * sorted[i]
+	}
+	sort.Float64s(sorted)
+	if mad := middle(sorted); mad > 0 {
+		return center, madScale * mad, true
+	}
+
+	// The median absolute deviation is 0 if more than half of the values
+	// are the same, as with integer gauges that mostly repeat a value.  Fall
+	// back to the standard deviation around the median.
+	return center, math.Sqrt(squares / float64(len(sorted))), true
+}
+
+func (m *median) add(v float64) {
+	if len(m.values) < cap(m.values) {
+		m.values = append(m.values, v)
+		return
+	}
+	m.values[m.next] = v
+	m.next = (m.next + 1) % len(m.values)
+}
+
+// middle returns the median of the sorted values.
+func middle(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,294 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Secret key for the HMAC-SHA256 of the "hash" action.
+  # hmac_key = ""
+
+  ## Each rule selects tags and fields by key, glob patterns are supported,
+  ## and optionally by a regular expression the value must match.  A value
+  ## is handled by the first matching rule only.  Except for "drop", rules
+  ## apply to tag values and string fields only.
+  ##
+  ## The action is one of:
+  ##   "hash"     - replace the value by its hex encoded HMAC-SHA256
+  ##   "truncate" - zero the host part of IP addresses in the value, values
+  ##                without addresses are cut to "length" characters if set
+  ##                or else replaced by the replacement
+  ##   "mask"     - replace the parts matching "pattern" by the replacement,
+  ##                or the whole value if there is no pattern
+  ##   "drop"     - remove the tag or field
+  # [[processors.redact.rule]]
+  #   tags = ["email", "user*"]
+  #   fields = []
+  #   pattern = ""
+  #   action = "hash"
+
+  # [[processors.redact.rule]]
+  #   tags = ["client_ip"]
+  #   action = "truncate"
+  #   ipv4_prefix = 24
+  #   ipv6_prefix = 64
+
+  # [[processors.redact.rule]]
+  #   fields = ["message"]
+  #   pattern = '[\w.+-]+@[\w-]+\.[\w.-]+'
+  #   action = "mask"
+  #   replacement = "<email>"
+`
+
+type Rule struct {
+	Tags    []string `toml:"tags"`
+	Fields  []string `toml:"fields"`
+	Pattern string   `toml:"pattern"`
+	Action  string   `toml:"action"`
+
+	IPv4Prefix  *int   `toml:"ipv4_prefix"`
+	IPv6Prefix  *int   `toml:"ipv6_prefix"`
+	Length      int    `toml:"length"`
+	Replacement string `toml:"replacement"`
+
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+	pattern     *regexp.Regexp
+	redact      func(string) string
+}
+
+type Redact struct {
+	HMACKey string `toml:"hmac_key"`
+	Rules   []Rule `toml:"rule"`
+}
+
+func (r *Redact) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Redact) Description() string {
+	return "Hash, truncate, mask or drop sensitive tag and field values"
+}
+
+func (r *Redact) Init() error {
+	for i := range r.Rules {
+		if err := r.Rules[i].init(r.HMACKey); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// init compiles the filters and creates the redact function of the rule.
+func (rule *Rule) init(key string) error {
+	if len(rule.Tags) == 0 && len(rule.Fields) == 0 {
+		return errors.New("no tags or fields set")
+	}
+
+	var err error
+	rule.tagFilter, err = filter.Compile(rule.Tags)
+	if err != nil {
+		return err
+	}
+	rule.fieldFilter, err = filter.Compile(rule.Fields)
+	if err != nil {
+		return err
+	}
+
+	if rule.Pattern != "" {
+		rule.pattern, err = regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+
+	switch rule.Action {
+	case "hash":
+		if key == "" {
+			return errors.New("hash action requires hmac_key")
+		}
+		secret := []byte(key)
+		rule.redact = func(v string) string {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(v))
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+	case "truncate":
+		ipv4Prefix, ipv6Prefix := 24, 64
+		if rule.IPv4Prefix != nil {
+			ipv4Prefix = *rule.IPv4Prefix
+		}
+		if rule.IPv6Prefix != nil {
+			ipv6Prefix = *rule.IPv6Prefix
+		}
+		if ipv4Prefix < 0 || ipv4Prefix > 32 {
+			return fmt.Errorf("invalid ipv4_prefix %d", ipv4Prefix)
+		}
+		if ipv6Prefix < 0 || ipv6Prefix > 128 {
+			return fmt.Errorf("invalid ipv6_prefix %d", ipv6Prefix)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = "***"
+		}
+		ipv4Mask := net.CIDRMask(ipv4Prefix, 32)
+		ipv6Mask := net.CIDRMask(ipv6Prefix, 128)
+		rule.redact = func(v string) string {
+			if truncated, ok := truncateIPs(v, ipv4Mask, ipv6Mask); ok {
+				return truncated
+			}
+			if rule.Length > 0 {
+				runes := []rune(v)
+				if len(runes) > rule.Length {
+					return string(runes[:rule.Length])
+				}
+				return v
+			}
+			// Fail closed, the value may hold an address in a form not
+			// recognized.
+			return rule.Replacement
+		}
+	case "mask":
+		if rule.Replacement == "" {
+			rule.Replacement = "***"
+		}
+		rule.redact = func(v string) string {
+			if rule.pattern == nil {
+				return rule.Replacement
+			}
+			return rule.pattern.ReplaceAllLiteralString(v, rule.Replacement)
+		}
+	case "drop":
+	default:
+		return fmt.Errorf("invalid action %q", rule.Action)
+	}
+	return nil
+}
+
+// ipCandidate matches the parts of a value that may be IP addresses,
+// possibly followed by a port.
+var ipCandidate = regexp.MustCompile(`[0-9A-Fa-f:.]*[:.][0-9A-Fa-f:.]*`)
+
+// truncateIPs zeroes the host part of all IP addresses in the value, such as
+// "10.0.0.1", "1.2.3.4:5678" or "[::1]:80".  It returns false if the value has
+// no addresses.
+func truncateIPs(v string, ipv4Mask, ipv6Mask net.IPMask) (string, bool) {
+	found := false
+	truncate := func(s string) (string, bool) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return s, false
+		}
+		found = true
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(ipv4Mask).String(), true
+		}
+		return ip.Mask(ipv6Mask).String(), true
+	}
+
+	result := ipCandidate.ReplaceAllStringFunc(v, func(s string) string {
+		if truncated, ok := truncate(s); ok {
+			return truncated
+		}
+		// An IPv4 address with a port, IPv6 addresses with a port are in
+		// brackets which are not part of the candidate.
+		if i := strings.LastIndexByte(s, ':'); i > 0 && !strings.Contains(s[:i], ":") {
+			if truncated, ok := truncate(s[:i]); ok {
+				return truncated + s[i:]
+			}
+		}
+		return s
+	})
+	return result, found
+}
+
+// matches returns true if the rule applies to the value.  Rules other than
+// drop only apply to strings.
+func (rule *Rule) matches(value interface{}) bool {
+	s, ok := value.(string)
+	if !ok {
+		return rule.redact == nil && rule.pattern == nil
+	}
+	return rule.pattern == nil || rule.pattern.MatchString(s)
+}
+
+func (r *Redact) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		// The tag and field lists are modified while iterating, so work on
+		// copies.
+		tags := make([]telegraf.Tag, 0, len(metric.TagList()))
+		for _, tag := range metric.TagList() {
+			tags = append(tags, *tag)
+		}
+		fields := make([]telegraf.Field, 0, len(metric.FieldList()))
+		for _, field := range metric.FieldList() {
+			fields = append(fields, *field)
+		}
+
+		for _, tag := range tags {
+			rule := r.findRule(tag.Key, tag.Value, true)
+			if rule == nil {
+				continue
+			}
+			if rule.redact == nil {
+				metric.RemoveTag(tag.Key)
+				continue
+			}
+			metric.AddTag(tag.Key, rule.redact(tag.Value))
+		}
+
+		for _, field := range fields {
+			rule := r.findRule(field.Key, field.Value, false)
+			if rule == nil {
+				continue
+			}
+			if rule.redact == nil {
+				metric.RemoveField(field.Key)
+				continue
+			}
+			metric.AddField(field.Key, rule.redact(field.Value.(string)))
+		}
+
+		if len(metric.FieldList()) == 0 {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// findRule returns the first rule selecting the tag or field.
+func (r *Redact) findRule(key string, value interface{}, isTag bool) *Rule {
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		f := rule.fieldFilter
+		if isTag {
+			f = rule.tagFilter
+		}
+		if f == nil || !f.Match(key) {
+			continue
+		}
+		if rule.matches(value) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func init() {
+	processors.Add("redact", func() telegraf.Processor {
+		return &Redact{}
+	})
+}
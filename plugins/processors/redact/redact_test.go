@@ -0,0 +1,227 @@
+package redact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	r := &Redact{
+		HMACKey: "secret",
+		Rules: []Rule{
+			{Tags: []string{"user*"}, Action: "hash"},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m := testutil.MustMetric("login",
+		map[string]string{"user": "alice", "user_id": "alice", "host": "a"},
+		map[string]interface{}{"count": 1},
+		time.Unix(0, 0),
+	)
+	actual := r.Apply(m)
+
+	// echo -n alice | openssl dgst -sha256 -hmac secret
+	hash := "4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8"
+	expected := []telegraf.Metric{
+		testutil.MustMetric("login",
+			map[string]string{"user": hash, "user_id": hash, "host": "a"},
+			map[string]interface{}{"count": 1},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestTruncate(t *testing.T) {
+	r := &Redact{
+		Rules: []Rule{
+			{Tags: []string{"*_ip"}, Action: "truncate"},
+			{Fields: []string{"user_agent"}, Action: "truncate", Length: 7},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m := testutil.MustMetric("http",
+		map[string]string{"client_ip": "192.168.17.42", "server_ip": "2001:db8:1:2:3:4:5:6"},
+		map[string]interface{}{"user_agent": "Mozilla/5.0", "bytes": 512},
+		time.Unix(0, 0),
+	)
+	actual := r.Apply(m)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("http",
+			map[string]string{"client_ip": "192.168.17.0", "server_ip": "2001:db8:1:2::"},
+			map[string]interface{}{"user_agent": "Mozilla", "bytes": 512},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestTruncateAddresses(t *testing.T) {
+	r := &Redact{
+		Rules: []Rule{
+			{Tags: []string{"addr"}, Action: "truncate"},
+			{Tags: []string{"any"}, Action: "truncate", IPv4Prefix: intPtr(0), IPv6Prefix: intPtr(0)},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	tests := []struct {
+		tag      string
+		value    string
+		expected string
+	}{
+		{tag: "addr", value: "1.2.3.4:5678", expected: "1.2.3.0:5678"},
+		{tag: "addr", value: "[2001:db8:1:2:3:4:5:6]:80", expected: "[2001:db8:1:2::]:80"},
+		{tag: "addr", value: "host 10.0.0.1 and 10.0.1.2", expected: "host 10.0.0.0 and 10.0.1.0"},
+		{tag: "addr", value: "1.2.3.4.5", expected: "***"},
+		{tag: "addr", value: "unknown", expected: "***"},
+		{tag: "any", value: "192.168.17.42", expected: "0.0.0.0"},
+		{tag: "any", value: "2001:db8::1", expected: "::"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			m := testutil.MustMetric("http",
+				map[string]string{tt.tag: tt.value},
+				map[string]interface{}{"bytes": 512},
+				time.Unix(0, 0),
+			)
+			actual := r.Apply(m)
+			require.Len(t, actual, 1)
+			v, _ := actual[0].GetTag(tt.tag)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	r := &Redact{
+		Rules: []Rule{
+			{Fields: []string{"message"}, Pattern: `[\w.+-]+@[\w-]+\.[\w.-]+`, Action: "mask", Replacement: "<email>"},
+			{Fields: []string{"message", "token"}, Action: "mask"},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m := testutil.MustMetric("log",
+		map[string]string{},
+		map[string]interface{}{
+			"message": "mail from bob@example.com to eve@example.org",
+			"token":   "abc123",
+		},
+		time.Unix(0, 0),
+	)
+	actual := r.Apply(m)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("log",
+			map[string]string{},
+			map[string]interface{}{
+				"message": "mail from <email> to <email>",
+				"token":   "***",
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDrop(t *testing.T) {
+	r := &Redact{
+		Rules: []Rule{
+			{Tags: []string{"*"}, Pattern: `^\d+\.\d+\.\d+\.\d+$`, Action: "drop"},
+			{Fields: []string{"session", "uid"}, Action: "drop"},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m1 := testutil.MustMetric("req",
+		map[string]string{"peer": "10.0.0.1", "host": "a"},
+		map[string]interface{}{"uid": 1001, "duration": 1.5},
+		time.Unix(0, 0),
+	)
+	m2 := testutil.MustMetric("session",
+		map[string]string{},
+		map[string]interface{}{"session": "abc"},
+		time.Unix(0, 0),
+	)
+	actual := r.Apply(m1, m2)
+
+	// Metrics without fields are dropped.
+	expected := []telegraf.Metric{
+		testutil.MustMetric("req",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"duration": 1.5},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestNonStringFields(t *testing.T) {
+	r := &Redact{
+		Rules: []Rule{
+			{Fields: []string{"*"}, Action: "mask"},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m := testutil.MustMetric("test",
+		map[string]string{},
+		map[string]interface{}{"value": 42, "name": "x"},
+		time.Unix(0, 0),
+	)
+	actual := r.Apply(m)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("test",
+			map[string]string{},
+			map[string]interface{}{"value": 42, "name": "***"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Redact
+	}{
+		{
+			name: "no keys",
+			r:    &Redact{Rules: []Rule{{Action: "drop"}}},
+		},
+		{
+			name: "hash without key",
+			r:    &Redact{Rules: []Rule{{Tags: []string{"user"}, Action: "hash"}}},
+		},
+		{
+			name: "invalid action",
+			r:    &Redact{Rules: []Rule{{Tags: []string{"user"}, Action: "encrypt"}}},
+		},
+		{
+			name: "invalid pattern",
+			r:    &Redact{Rules: []Rule{{Tags: []string{"user"}, Pattern: "(", Action: "drop"}}},
+		},
+		{
+			name: "invalid prefix",
+			r:    &Redact{Rules: []Rule{{Tags: []string{"ip"}, Action: "truncate", IPv4Prefix: intPtr(33)}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.r.Init())
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
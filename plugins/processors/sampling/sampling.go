@@ -0,0 +1,157 @@
+package sampling
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Sampling method, one of:
+  ##   "series"   - keep the fraction "rate" of the series, chosen by a hash
+  ##                of the measurement name and tags, so a series is either
+  ##                always kept or always dropped
+  ##   "random"   - keep each metric with probability "rate"
+  ##   "decimate" - keep the first metric of each series per "interval"
+  # method = "series"
+
+  ## Fraction of series or metrics to keep, for the "series" and "random"
+  ## methods.
+  # rate = 0.1
+
+  ## Field set to the rate on the kept metrics, for the "series" and "random"
+  ## methods.  Divide values by the rate to estimate the totals.  Set to an
+  ## empty string to not add the field.
+  # sample_rate_field = "sample_rate"
+
+  ## Interval for the "decimate" method, intervals are aligned to the metric
+  ## time.
+  # interval = "1m"
+`
+
+type Sampling struct {
+	Method          string            `toml:"method"`
+	Rate            float64           `toml:"rate"`
+	SampleRateField string            `toml:"sample_rate_field"`
+	Interval        internal.Duration `toml:"interval"`
+
+	rand     *rand.Rand
+	now      func() time.Time
+	last     map[uint64]*decimated
+	lastTrim time.Time
+}
+
+// decimated is the state of a series for the decimate method.
+type decimated struct {
+	// start is the start of the interval of the last metric kept.
+	start time.Time
+	// seen is the wall clock time the series was last seen, the metric times
+	// of different series may not be comparable.
+	seen time.Time
+}
+
+func (s *Sampling) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sampling) Description() string {
+	return "Keep a sample of the series or metrics"
+}
+
+func (s *Sampling) Init() error {
+	switch s.Method {
+	case "series", "random":
+		if s.Rate <= 0 || s.Rate > 1 {
+			return fmt.Errorf("rate must be in (0, 1], got %v", s.Rate)
+		}
+	case "decimate":
+		if s.Interval.Duration <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid method %q", s.Method)
+	}
+
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	s.last = make(map[uint64]*decimated)
+	s.lastTrim = s.now()
+	return nil
+}
+
+func (s *Sampling) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if !s.keep(m) {
+			m.Drop()
+			continue
+		}
+		if s.Method != "decimate" && s.SampleRateField != "" {
+			m.AddField(s.SampleRateField, s.Rate)
+		}
+		out = append(out, m)
+	}
+	s.trim()
+	return out
+}
+
+func (s *Sampling) keep(m telegraf.Metric) bool {
+	switch s.Method {
+	case "series":
+		return float64(m.HashID())/math.MaxUint64 < s.Rate
+	case "random":
+		return s.rand.Float64() < s.Rate
+	case "decimate":
+		id := m.HashID()
+		start := m.Time().Truncate(s.Interval.Duration)
+		last, ok := s.last[id]
+		if !ok {
+			last = &decimated{}
+			s.last[id] = last
+		}
+		last.seen = s.now()
+		if ok && !start.After(last.start) {
+			return false
+		}
+		last.start = start
+		return true
+	}
+	return true
+}
+
+// trim removes the series not seen within the last two intervals, so series
+// that stopped do not use memory forever.
+func (s *Sampling) trim() {
+	now := s.now()
+	if s.Method != "decimate" || now.Sub(s.lastTrim) < s.Interval.Duration {
+		return
+	}
+	s.lastTrim = now
+
+	limit := now.Add(-2 * s.Interval.Duration)
+	for id, last := range s.last {
+		if last.seen.Before(limit) {
+			delete(s.last, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("sampling", func() telegraf.Processor {
+		return &Sampling{
+			Method:          "series",
+			Rate:            0.1,
+			SampleRateField: "sample_rate",
+			Interval:        internal.Duration{Duration: time.Minute},
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package sampling
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSampling(method string) *Sampling {
+	return &Sampling{
+		Method:          method,
+		Rate:            0.25,
+		SampleRateField: "sample_rate",
+		Interval:        internal.Duration{Duration: time.Minute},
+		rand:            rand.New(rand.NewSource(1)),
+	}
+}
+
+func series(n int, tm time.Time) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, testutil.MustMetric("requests",
+			map[string]string{"path": "/" + strconv.Itoa(i)},
+			map[string]interface{}{"count": 1},
+			tm,
+		))
+	}
+	return metrics
+}
+
+func paths(metrics []telegraf.Metric) []string {
+	result := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		path, _ := m.GetTag("path")
+		result = append(result, path)
+	}
+	return result
+}
+
+func TestSeries(t *testing.T) {
+	s := newSampling("series")
+	require.NoError(t, s.Init())
+
+	first := s.Apply(series(1000, time.Unix(0, 0))...)
+	require.InDelta(t, 250, len(first), 50)
+	for _, m := range first {
+		rate, ok := m.GetField("sample_rate")
+		require.True(t, ok)
+		require.Equal(t, 0.25, rate)
+	}
+
+	// The same series are kept every time.
+	second := s.Apply(series(1000, time.Unix(10, 0))...)
+	require.Equal(t, paths(first), paths(second))
+}
+
+func TestRandom(t *testing.T) {
+	s := newSampling("random")
+	s.SampleRateField = ""
+	require.NoError(t, s.Init())
+
+	kept := 0
+	for i := 0; i < 10; i++ {
+		for _, m := range s.Apply(series(100, time.Unix(int64(i), 0))...) {
+			_, ok := m.GetField("sample_rate")
+			require.False(t, ok)
+			kept++
+		}
+	}
+	require.InDelta(t, 250, kept, 50)
+}
+
+func TestDecimate(t *testing.T) {
+	s := newSampling("decimate")
+	require.NoError(t, s.Init())
+
+	start := time.Now().Truncate(time.Minute)
+	var kept []telegraf.Metric
+	for _, offset := range []time.Duration{0, 20 * time.Second, 59 * time.Second, 61 * time.Second, 3 * time.Minute} {
+		in := testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage": offset.Seconds()},
+			start.Add(offset),
+		)
+		other := testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu1"},
+			map[string]interface{}{"usage": offset.Seconds()},
+			start.Add(offset),
+		)
+		kept = append(kept, s.Apply(in, other)...)
+	}
+
+	expected := []telegraf.Metric{}
+	for _, offset := range []time.Duration{0, 61 * time.Second, 3 * time.Minute} {
+		for _, cpu := range []string{"cpu0", "cpu1"} {
+			expected = append(expected, testutil.MustMetric("cpu",
+				map[string]string{"cpu": cpu},
+				map[string]interface{}{"usage": offset.Seconds()},
+				start.Add(offset),
+			))
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, kept)
+}
+
+func TestDecimateSkewedClocks(t *testing.T) {
+	now := time.Unix(1600000000, 0).Truncate(time.Minute)
+	s := newSampling("decimate")
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.Init())
+
+	// The clock of cpu1 lags by a day, it is still decimated as trimming
+	// uses the wall clock.
+	metric := func(cpu string, skew time.Duration) telegraf.Metric {
+		return testutil.MustMetric("cpu",
+			map[string]string{"cpu": cpu},
+			map[string]interface{}{"usage": 1.0},
+			now.Add(-skew),
+		)
+	}
+	var kept []string
+	for i := 0; i < 8; i++ {
+		for _, m := range s.Apply(metric("cpu0", 0), metric("cpu1", 24*time.Hour)) {
+			cpu, _ := m.GetTag("cpu")
+			kept = append(kept, cpu)
+		}
+		now = now.Add(20 * time.Second)
+	}
+	require.Equal(t, []string{"cpu0", "cpu1", "cpu0", "cpu1", "cpu0", "cpu1"}, kept)
+}
+
+func TestDecimateTrim(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := newSampling("decimate")
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.Init())
+
+	metric := func(cpu string) telegraf.Metric {
+		return testutil.MustMetric("cpu",
+			map[string]string{"cpu": cpu},
+			map[string]interface{}{"usage": 1.0},
+			now,
+		)
+	}
+	require.Len(t, s.Apply(metric("cpu0"), metric("cpu1")), 2)
+
+	// The series not seen within two intervals is removed.
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Minute)
+		require.Len(t, s.Apply(metric("cpu1")), 1)
+	}
+	require.Len(t, s.last, 1)
+	require.Contains(t, s.last, metric("cpu1").HashID())
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Sampling)
+	}{
+		{name: "invalid method", modify: func(s *Sampling) { s.Method = "all" }},
+		{name: "zero rate", modify: func(s *Sampling) { s.Rate = 0 }},
+		{name: "rate above one", modify: func(s *Sampling) { s.Rate = 2 }},
+		{name: "zero interval", modify: func(s *Sampling) { s.Method = "decimate"; s.Interval.Duration = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSampling("series")
+			tt.modify(s)
+			require.Error(t, s.Init())
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package scale
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Each scaling rule selects fields by name, glob patterns are supported,
+  ## and converts them using exactly one of: an input and output range, a
+  ## factor and offset, or a named unit conversion.  A field is converted by
+  ## the first matching rule only.  The converted values are floats.  If the
+  ## suffix is set the converted field is renamed by appending it.
+
+  ## Map the input range linearly to the output range.
+  # [[processors.scale.scaling]]
+  #   fields = ["level"]
+  #   input_minimum = 0.0
+  #   input_maximum = 1.0
+  #   output_minimum = 0.0
+  #   output_maximum = 100.0
+
+  ## Multiply by the factor and add the offset.
+  # [[processors.scale.scaling]]
+  #   fields = ["*_ms"]
+  #   factor = 0.001
+  #   offset = 0.0
+
+  ## Convert between units, see the README for the list of units.
+  # [[processors.scale.scaling]]
+  #   fields = ["temp_*"]
+  #   from = "celsius"
+  #   to = "fahrenheit"
+  #   suffix = "_f"
+`
+
+type Scaling struct {
+	Fields []string `toml:"fields"`
+
+	InputMinimum  *float64 `toml:"input_minimum"`
+	InputMaximum  *float64 `toml:"input_maximum"`
+	OutputMinimum *float64 `toml:"output_minimum"`
+	OutputMaximum *float64 `toml:"output_maximum"`
+
+	Factor *float64 `toml:"factor"`
+	Offset *float64 `toml:"offset"`
+
+	From string `toml:"from"`
+	To   string `toml:"to"`
+
+	Suffix string `toml:"suffix"`
+
+	fieldFilter filter.Filter
+	convert     func(float64) float64
+}
+
+type Scale struct {
+	Scalings []Scaling `toml:"scaling"`
+}
+
+func (s *Scale) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Scale) Description() string {
+	return "Scale numeric fields or convert them between units"
+}
+
+func (s *Scale) Init() error {
+	for i := range s.Scalings {
+		if err := s.Scalings[i].init(); err != nil {
+			return fmt.Errorf("scaling %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// init creates the conversion function of the rule.
+func (sc *Scaling) init() error {
+	if len(sc.Fields) == 0 {
+		return errors.New("no fields set")
+	}
+	var err error
+	sc.fieldFilter, err = filter.Compile(sc.Fields)
+	if err != nil {
+		return err
+	}
+
+	isRange := sc.InputMinimum != nil || sc.InputMaximum != nil || sc.OutputMinimum != nil || sc.OutputMaximum != nil
+	isFactor := sc.Factor != nil || sc.Offset != nil
+	isUnit := sc.From != "" || sc.To != ""
+
+	modes := 0
+	for _, set := range []bool{isRange, isFactor, isUnit} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return errors.New("exactly one of range, factor/offset or from/to must be set")
+	}
+
+	switch {
+	case isRange:
+		if sc.InputMinimum == nil || sc.InputMaximum == nil || sc.OutputMinimum == nil || sc.OutputMaximum == nil {
+			return errors.New("all of input_minimum, input_maximum, output_minimum and output_maximum must be set")
+		}
+		if *sc.InputMinimum == *sc.InputMaximum {
+			return errors.New("input_minimum and input_maximum must differ")
+		}
+		inMin, inMax := *sc.InputMinimum, *sc.InputMaximum
+		outMin, outMax := *sc.OutputMinimum, *sc.OutputMaximum
+		sc.convert = func(v float64) float64 {
+			return (v-inMin)*(outMax-outMin)/(inMax-inMin) + outMin
+		}
+	case isFactor:
+		factor, offset := 1.0, 0.0
+		if sc.Factor != nil {
+			factor = *sc.Factor
+		}
+		if sc.Offset != nil {
+			offset = *sc.Offset
+		}
+		sc.convert = func(v float64) float64 {
+			return v*factor + offset
+		}
+	case isUnit:
+		sc.convert, err = conversion(sc.From, sc.To)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scale) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		// The field list is modified while iterating, so work on a copy.
+		fields := make([]telegraf.Field, 0, len(metric.FieldList()))
+		for _, field := range metric.FieldList() {
+			fields = append(fields, *field)
+		}
+
+		for _, field := range fields {
+			v, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			for i := range s.Scalings {
+				sc := &s.Scalings[i]
+				if !sc.fieldFilter.Match(field.Key) {
+					continue
+				}
+				if sc.Suffix != "" {
+					metric.RemoveField(field.Key)
+				}
+				metric.AddField(field.Key+sc.Suffix, sc.convert(v))
+				break
+			}
+		}
+	}
+	return in
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("scale", func() telegraf.Processor {
+		return &Scale{}
+	})
+}
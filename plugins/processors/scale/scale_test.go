@@ -0,0 +1,163 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name     string
+		scalings []Scaling
+		fields   map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "range",
+			scalings: []Scaling{
+				{
+					Fields:        []string{"level"},
+					InputMinimum:  float(0),
+					InputMaximum:  float(4095),
+					OutputMinimum: float(-10),
+					OutputMaximum: float(10),
+				},
+			},
+			fields:   map[string]interface{}{"level": int64(4095), "raw": int64(4095)},
+			expected: map[string]interface{}{"level": 10.0, "raw": int64(4095)},
+		},
+		{
+			name: "factor and offset",
+			scalings: []Scaling{
+				{
+					Fields: []string{"*_ms"},
+					Factor: float(0.001),
+				},
+			},
+			fields:   map[string]interface{}{"response_ms": uint64(250), "status": "ok"},
+			expected: map[string]interface{}{"response_ms": 0.25, "status": "ok"},
+		},
+		{
+			name: "unit with suffix",
+			scalings: []Scaling{
+				{
+					Fields: []string{"temp_*"},
+					From:   "celsius",
+					To:     "fahrenheit",
+					Suffix: "_f",
+				},
+			},
+			fields:   map[string]interface{}{"temp_cpu": 100.0, "temp_gpu": -40.0},
+			expected: map[string]interface{}{"temp_cpu_f": 212.0, "temp_gpu_f": -40.0},
+		},
+		{
+			name: "first matching rule wins",
+			scalings: []Scaling{
+				{
+					Fields: []string{"used"},
+					From:   "kibibytes",
+					To:     "bytes",
+				},
+				{
+					Fields: []string{"*"},
+					Factor: float(2),
+				},
+			},
+			fields:   map[string]interface{}{"used": int64(2), "free": int64(3)},
+			expected: map[string]interface{}{"used": 2048.0, "free": 6.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scale{Scalings: tt.scalings}
+			require.NoError(t, s.Init())
+
+			m := testutil.MustMetric("m", map[string]string{}, tt.fields, time.Unix(0, 0))
+			out := s.Apply(m)
+
+			expected := []telegraf.Metric{
+				testutil.MustMetric("m", map[string]string{}, tt.expected, time.Unix(0, 0)),
+			}
+			testutil.RequireMetricsEqual(t, expected, out)
+		})
+	}
+}
+
+func TestConversion(t *testing.T) {
+	tests := []struct {
+		from, to string
+		in, out  float64
+	}{
+		{"kelvin", "celsius", 0, -273.15},
+		{"fahrenheit", "kelvin", 32, 273.15},
+		{"bits", "kilobytes", 8000, 1},
+		{"mebibytes", "kibibytes", 1, 1024},
+		{"milliseconds", "seconds", 1500, 1.5},
+		{"hours", "minutes", 2, 120},
+		{"celsius", "fahrenheit", 37, 98.6},
+		{"fahrenheit", "celsius", 212, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			convert, err := conversion(tt.from, tt.to)
+			require.NoError(t, err)
+			require.Equal(t, tt.out, convert(tt.in))
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		scaling Scaling
+	}{
+		{
+			name:    "no fields",
+			scaling: Scaling{Factor: float(2)},
+		},
+		{
+			name:    "no mode",
+			scaling: Scaling{Fields: []string{"a"}},
+		},
+		{
+			name:    "two modes",
+			scaling: Scaling{Fields: []string{"a"}, Factor: float(2), From: "bytes", To: "bits"},
+		},
+		{
+			name:    "incomplete range",
+			scaling: Scaling{Fields: []string{"a"}, InputMinimum: float(0), InputMaximum: float(1)},
+		},
+		{
+			name: "empty input range",
+			scaling: Scaling{
+				Fields:        []string{"a"},
+				InputMinimum:  float(1),
+				InputMaximum:  float(1),
+				OutputMinimum: float(0),
+				OutputMaximum: float(1),
+			},
+		},
+		{
+			name:    "unknown unit",
+			scaling: Scaling{Fields: []string{"a"}, From: "bytes", To: "nibbles"},
+		},
+		{
+			name:    "different quantities",
+			scaling: Scaling{Fields: []string{"a"}, From: "bytes", To: "seconds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scale{Scalings: []Scaling{tt.scaling}}
+			require.Error(t, s.Init())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package scale
+
+import "fmt"
+
+// unit converts to the base unit of its quantity as
+// (value - zero) * num / den.  The scale is kept as a fraction and the
+// conversion is done in steps to avoid rounding errors, so that for example
+// 100 degree celsius are exactly 212 degree fahrenheit.
+type unit struct {
+	quantity string
+	zero     float64
+	num      float64
+	den      float64
+}
+
+var units = map[string]unit{
+	// Temperature, base unit celsius
+	"celsius":    {"temperature", 0, 1, 1},
+	"kelvin":     {"temperature", 273.15, 1, 1},
+	"fahrenheit": {"temperature", 32, 5, 9},
+
+	// Information, base unit bytes
+	"bits":      {"information", 0, 1, 8},
+	"bytes":     {"information", 0, 1, 1},
+	"kilobytes": {"information", 0, 1e3, 1},
+	"megabytes": {"information", 0, 1e6, 1},
+	"gigabytes": {"information", 0, 1e9, 1},
+	"terabytes": {"information", 0, 1e12, 1},
+	"kibibytes": {"information", 0, 1 << 10, 1},
+	"mebibytes": {"information", 0, 1 << 20, 1},
+	"gibibytes": {"information", 0, 1 << 30, 1},
+	"tebibytes": {"information", 0, 1 << 40, 1},
+
+	// Time, base unit nanoseconds
+	"nanoseconds":  {"time", 0, 1, 1},
+	"microseconds": {"time", 0, 1e3, 1},
+	"milliseconds": {"time", 0, 1e6, 1},
+	"seconds":      {"time", 0, 1e9, 1},
+	"minutes":      {"time", 0, 60e9, 1},
+	"hours":        {"time", 0, 3600e9, 1},
+	"days":         {"time", 0, 86400e9, 1},
+}
+
+// conversion returns a function converting values from one unit to another.
+func conversion(from, to string) (func(float64) float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.quantity != t.quantity {
+		return nil, fmt.Errorf("cannot convert %s %q to %s %q", f.quantity, from, t.quantity, to)
+	}
+
+	return func(v float64) float64 {
+		base := (v - f.zero) * f.num / f.den
+		return base*t.den/t.num + t.zero
+	}, nil
+}
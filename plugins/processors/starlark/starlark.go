@@ -1,13 +1,13 @@
 package starlark
 
 import (
-	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
 	"github.com/influxdata/telegraf/plugins/processors"
-	"go.starlark.net/resolve"
 	"go.starlark.net/starlark"
 )
 
@@ -26,75 +26,65 @@ def apply(metric):
 
   ## File containing a Starlark script.
   # script = "/usr/local/bin/myscript.star"
+
+  ## File used to save the state dict and to restore it on startup.  The
+  ## state must only contain values that can be encoded as JSON.
+  # state_file = "/var/lib/telegraf/starlark_state.json"
+
+  ## Minimum time between saving the state while processing metrics, so it
+  ## is not lost if Telegraf crashes.  The state is always saved when
+  ## Telegraf stops.
+  # state_save_interval = "10s"
+
+  ## Values that are predeclared in the script as global constants.
+  # [processors.starlark.constants]
+  #   max_size = 10
+  #   threshold = 0.75
+  #   default_name = "Julia"
 `
 )
 
 type Starlark struct {
-	Source string `toml:"source"`
-	Script string `toml:"script"`
+	Source            string                 `toml:"source"`
+	Script            string                 `toml:"script"`
+	StateFile         string                 `toml:"state_file"`
+	StateSaveInterval internal.Duration      `toml:"state_save_interval"`
+	Constants         map[string]interface{} `toml:"constants"`
 
 	Log telegraf.Logger `toml:"-"`
 
-	thread    *starlark.Thread
+	program   *common.Program
 	applyFunc *starlark.Function
 	args      starlark.Tuple
 	results   []telegraf.Metric
 }
 
 func (s *Starlark) Init() error {
-	if s.Source == "" && s.Script == "" {
-		return errors.New("one of source or script must be set")
-	}
-	if s.Source != "" && s.Script != "" {
-		return errors.New("both source or script cannot be set")
-	}
-
-	s.thread = &starlark.Thread{
-		Print: func(_ *starlark.Thread, msg string) { s.Log.Debug(msg) },
-	}
-
-	builtins := starlark.StringDict{}
-	builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
-	builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
-
-	program, err := s.sourceProgram(builtins)
+	var err error
+	s.program, err = common.Load(&common.Config{
+		Name:              "processor.starlark",
+		Source:            s.Source,
+		Script:            s.Script,
+		StateFile:         s.StateFile,
+		StateSaveInterval: s.StateSaveInterval.Duration,
+		Constants:         s.Constants,
+		Log:               s.Log,
+	})
 	if err != nil {
 		return err
 	}
 
-	// Execute source
-	globals, err := program.Init(s.thread, builtins)
+	// The source should define an apply function.
+	s.applyFunc, err = s.program.Function("apply", 1)
 	if err != nil {
 		return err
 	}
 
-	// Freeze the global state.  This prevents modifications to the processor
-	// state and prevents scripts from containing errors storing tracking
-	// metrics.  Tasks that require global state will not be possible due to
-	// this, so maybe we should relax this in the future.
-	globals.Freeze()
-
-	// The source should define an apply function.
-	apply := globals["apply"]
-
-	if apply == nil {
-		return errors.New("apply is not defined")
-	}
-
-	var ok bool
-	if s.applyFunc, ok = apply.(*starlark.Function); !ok {
-		return errors.New("apply is not a function")
-	}
-
-	if s.applyFunc.NumParams() != 1 {
-		return errors.New("apply function must take one parameter")
-	}
-
 	// Reusing the same metric wrapper to skip an allocation.  This will cause
 	// any saved references to point to the new metric, but due to freezing the
-	// globals none should exist.
+	// globals none should exist unless a metric is stored in the state.
 	s.args = make(starlark.Tuple, 1)
-	s.args[0] = &Metric{}
+	s.args[0] = &common.Metric{}
 
 	// Preallocate a slice for return values.
 	s.results = make([]telegraf.Metric, 0, 10)
@@ -102,15 +92,6 @@ func (s *Starlark) Init() error {
 	return nil
 }
 
-func (s *Starlark) sourceProgram(builtins starlark.StringDict) (*starlark.Program, error) {
-	if s.Source != "" {
-		_, program, err := starlark.SourceProgram("processor.starlark", s.Source, builtins.Has)
-		return program, err
-	}
-	_, program, err := starlark.SourceProgram(s.Script, nil, builtins.Has)
-	return program, err
-}
-
 func (s *Starlark) SampleConfig() string {
 	return sampleConfig
 }
@@ -124,15 +105,10 @@ func (s *Starlark) Start(acc telegraf.Accumulator) error {
 }
 
 func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
-	s.args[0].(*Metric).Wrap(metric)
+	s.args[0].(*common.Metric).Wrap(metric)
 
-	rv, err := starlark.Call(s.thread, s.applyFunc, s.args, nil)
+	rv, err := s.program.Call(s.applyFunc, s.args)
 	if err != nil {
-		if err, ok := err.(*starlark.EvalError); ok {
-			for _, line := range strings.Split(err.Backtrace(), "\n") {
-				s.Log.Error(line)
-			}
-		}
 		metric.Reject()
 		return err
 	}
@@ -144,7 +120,7 @@ func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
 		var v starlark.Value
 		for iter.Next(&v) {
 			switch v := v.(type) {
-			case *Metric:
+			case *common.Metric:
 				m := v.Unwrap()
 				if containsMetric(s.results, m) {
 					s.Log.Errorf("Duplicate metric reference detected")
@@ -168,7 +144,7 @@ func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
 			s.results[i] = nil
 		}
 		s.results = s.results[:0]
-	case *Metric:
+	case *common.Metric:
 		m := rv.Unwrap()
 
 		// If the script returned a different metric, mark this metric as
@@ -186,7 +162,7 @@ func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
 }
 
 func (s *Starlark) Stop() error {
-	return nil
+	return s.program.Close()
 }
 
 func containsMetric(metrics []telegraf.Metric, metric telegraf.Metric) bool {
@@ -198,18 +174,10 @@ func containsMetric(metrics []telegraf.Metric, metric telegraf.Metric) bool {
 	return false
 }
 
-func init() {
-	// https://github.com/bazelbuild/starlark/issues/20
-	resolve.AllowNestedDef = true
-	resolve.AllowLambda = true
-	resolve.AllowFloat = true
-	resolve.AllowSet = true
-	resolve.AllowGlobalReassign = true
-	resolve.AllowRecursion = true
-}
-
 func init() {
 	processors.AddStreaming("starlark", func() telegraf.StreamingProcessor {
-		return &Starlark{}
+		return &Starlark{
+			StateSaveInterval: internal.Duration{Duration: 10 * time.Second},
+		}
 	})
 }
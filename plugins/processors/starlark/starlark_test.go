@@ -1,6 +1,9 @@
 package starlark
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -2489,6 +2492,61 @@ func TestScript(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "rate",
+			plugin: &Starlark{
+				Script: "testdata/rate.star",
+				Log:    testutil.Logger{},
+			},
+			input: []telegraf.Metric{
+				testutil.MustMetric("net",
+					map[string]string{"host": "a"},
+					map[string]interface{}{"count": 10},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("net",
+					map[string]string{"host": "a"},
+					map[string]interface{}{"count": 30},
+					time.Unix(10, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("net",
+					map[string]string{"host": "a"},
+					map[string]interface{}{
+						"count": 30,
+						"rate":  2.0,
+					},
+					time.Unix(10, 0),
+				),
+			},
+		},
+		{
+			name: "json nested",
+			plugin: &Starlark{
+				Script:    "testdata/json_nested.star",
+				Constants: map[string]interface{}{"separator": "_"},
+				Log:       testutil.Logger{},
+			},
+			input: []telegraf.Metric{
+				testutil.MustMetric("event",
+					map[string]string{},
+					map[string]interface{}{"value": `{"a": 1, "b": {"c": 2.5, "d": "x"}}`},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("event",
+					map[string]string{},
+					map[string]interface{}{
+						"a":   1,
+						"b_c": 2.5,
+						"b_d": "x",
+					},
+					time.Unix(0, 0),
+				),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -2518,6 +2576,210 @@ func TestScript(t *testing.T) {
 	}
 }
 
+func TestModules(t *testing.T) {
+	plugin := &Starlark{
+		Source: `
+load("json.star", "json")
+load("math.star", "math")
+load("time.star", "time")
+load("logging.star", "log")
+
+def apply(metric):
+	log.info("applying")
+	metric.fields["json"] = json.encode({"a": [1, 2.5, True, None]})
+	metric.fields["sqrt"] = math.sqrt(metric.fields["value"])
+	metric.fields["date"] = time.format(metric.time, "2006-01-02")
+	metric.time = time.parse("2006-01-02", "2020-06-23") + time.hour
+	return metric
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	require.NoError(t, plugin.Add(testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 16.0},
+		time.Unix(1592846400, 0),
+	), &acc))
+	require.NoError(t, plugin.Stop())
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 16.0,
+				"json":  `{"a":[1,2.5,true,null]}`,
+				"sqrt":  4.0,
+				"date":  "2020-06-22",
+			},
+			time.Unix(1592874000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestConstants(t *testing.T) {
+	plugin := &Starlark{
+		Source: `
+def apply(metric):
+	metric.fields["max"] = max_size
+	metric.fields["name"] = names[1]
+	return metric
+`,
+		Constants: map[string]interface{}{
+			"max_size": int64(10),
+			"names":    []interface{}{"a", "b"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Add(testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	), &acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{"value": 1, "max": 10, "name": "b"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source := `
+def apply(metric):
+	state["count"] = state.get("count", 0) + 1
+	metric.fields["count"] = state["count"]
+	return metric
+`
+	m := testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	)
+
+	var acc testutil.Accumulator
+	for i := 0; i < 2; i++ {
+		plugin := &Starlark{
+			Source:    source,
+			StateFile: filepath.Join(dir, "state.json"),
+			Log:       testutil.Logger{},
+		}
+		require.NoError(t, plugin.Init())
+		require.NoError(t, plugin.Add(m.Copy(), &acc))
+		require.NoError(t, plugin.Stop())
+	}
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"value": int64(1), "count": int64(2)}, metrics[1].Fields())
+}
+
+func TestStateSavedAfterCall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Starlark{
+		Source: `
+def apply(metric):
+	state["count"] = state.get("count", 0) + 1
+	state["metrics"] = [metric.name]
+	return metric
+`,
+		StateFile: filepath.Join(dir, "state.json"),
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Add(testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	), &acc))
+
+	// Saved without stopping, as if Telegraf crashed afterwards.
+	octets, err := ioutil.ReadFile(filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"count": 1, "metrics": ["m"]}`, string(octets))
+}
+
+func TestStateRejectsMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Starlark{
+		Source: `
+def apply(metric):
+	state["count"] = 1
+	state["nested"] = []
+	state["nested"].append(metric)
+	state["last"] = metric
+	return metric
+`,
+		StateFile: filepath.Join(dir, "state.json"),
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	err = plugin.Add(testutil.MustMetric("m",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	), &acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "metrics cannot be stored in the state")
+
+	// The values that can be saved are still saved.
+	require.NoError(t, plugin.Stop())
+	octets, err := ioutil.ReadFile(filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"count": 1}`, string(octets))
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name:   "missing file",
+			script: `load("testdata/missing.star", "f")`,
+		},
+		{
+			name:   "missing symbol",
+			script: `load("json.star", "yaml")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Starlark{
+				Source: tt.script + `
+def apply(metric):
+	return metric
+`,
+				Log: testutil.Logger{},
+			}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
+
 // Benchmarks modify the metric in place, so the scripts shouldn't modify the
 // metric.
 func Benchmark(b *testing.B) {
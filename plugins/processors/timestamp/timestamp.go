@@ -0,0 +1,210 @@
+package timestamp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Replace the metric time with the time the metric is processed.
+  # use_receive_time = false
+
+  ## Parse the metric time from a field or tag, using the first of the
+  ## formats that succeeds.  A format is one of "unix", "unix_ms", "unix_us",
+  ## "unix_ns" or a Go time layout using the reference time
+  ## "Mon Jan 2 15:04:05 -0700 MST 2006".  The metric time is unchanged if
+  ## the field or tag is missing or can not be parsed.
+  # source_field = ""
+  # source_tag = ""
+  # formats = ["unix"]
+
+  ## Timezone of time layouts without a zone.  This can be set to one of
+  ## "UTC", "Local", or to a location name in the IANA Time Zone database.
+  # timezone = "UTC"
+
+  ## Remove the source field or tag after the time is parsed.
+  # remove_source = false
+
+  ## Duration added to the metric time, can be negative.
+  # offset = "0s"
+
+  ## Maximum duration the metric time, after the offset is added, may be
+  ## before or after the time the metric is processed, 0 for no limit.
+  ## Metrics outside the limits are either dropped or clamped to the limit.
+  # max_past = "0s"
+  # max_future = "0s"
+  # out_of_range = "drop"
+
+  ## Truncate or round the metric time to a multiple of the precision.
+  # precision = "0s"
+  # rounding = "truncate"
+`
+
+type Timestamp struct {
+	UseReceiveTime bool              `toml:"use_receive_time"`
+	SourceField    string            `toml:"source_field"`
+	SourceTag      string            `toml:"source_tag"`
+	Formats        []string          `toml:"formats"`
+	Timezone       string            `toml:"timezone"`
+	RemoveSource   bool              `toml:"remove_source"`
+	Offset         internal.Duration `toml:"offset"`
+	MaxPast        internal.Duration `toml:"max_past"`
+	MaxFuture      internal.Duration `toml:"max_future"`
+	OutOfRange     string            `toml:"out_of_range"`
+	Precision      internal.Duration `toml:"precision"`
+	Rounding       string            `toml:"rounding"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	now func() time.Time
+}
+
+func (t *Timestamp) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Timestamp) Description() string {
+	return "Replace, parse, shift, limit and round the metric time"
+}
+
+func (t *Timestamp) Init() error {
+	sources := 0
+	for _, set := range []bool{t.UseReceiveTime, t.SourceField != "", t.SourceTag != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of use_receive_time, source_field or source_tag can be set")
+	}
+	if sources > 0 && !t.UseReceiveTime && len(t.Formats) == 0 {
+		return errors.New("formats must not be empty")
+	}
+
+	if _, err := time.LoadLocation(t.Timezone); err != nil {
+		return err
+	}
+
+	if t.MaxPast.Duration < 0 || t.MaxFuture.Duration < 0 {
+		return errors.New("max_past and max_future must not be negative")
+	}
+	switch t.OutOfRange {
+	case "drop", "clamp":
+	default:
+		return fmt.Errorf("invalid out_of_range %q", t.OutOfRange)
+	}
+
+	if t.Precision.Duration < 0 {
+		return errors.New("precision must not be negative")
+	}
+	switch t.Rounding {
+	case "truncate", "round":
+	default:
+		return fmt.Errorf("invalid rounding %q", t.Rounding)
+	}
+
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return nil
+}
+
+func (t *Timestamp) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := t.now()
+	out := in[:0]
+	for _, m := range in {
+		tm, ok := t.process(m, now)
+		if !ok {
+			m.Drop()
+			continue
+		}
+		m.SetTime(tm)
+		out = append(out, m)
+	}
+	return out
+}
+
+// process returns the new time of the metric, or false if the metric should
+// be dropped.
+func (t *Timestamp) process(m telegraf.Metric, now time.Time) (time.Time, bool) {
+	tm := m.Time()
+	switch {
+	case t.UseReceiveTime:
+		tm = now
+	case t.SourceField != "":
+		if v, ok := m.GetField(t.SourceField); ok {
+			if parsed, ok := t.parse(v); ok {
+				tm = parsed
+				if t.RemoveSource {
+					m.RemoveField(t.SourceField)
+				}
+			}
+		}
+	case t.SourceTag != "":
+		if v, ok := m.GetTag(t.SourceTag); ok {
+			if parsed, ok := t.parse(v); ok {
+				tm = parsed
+				if t.RemoveSource {
+					m.RemoveTag(t.SourceTag)
+				}
+			}
+		}
+	}
+
+	tm = tm.Add(t.Offset.Duration)
+
+	if t.MaxPast.Duration > 0 {
+		if limit := now.Add(-t.MaxPast.Duration); tm.Before(limit) {
+			if t.OutOfRange == "drop" {
+				return tm, false
+			}
+			tm = limit
+		}
+	}
+	if t.MaxFuture.Duration > 0 {
+		if limit := now.Add(t.MaxFuture.Duration); tm.After(limit) {
+			if t.OutOfRange == "drop" {
+				return tm, false
+			}
+			tm = limit
+		}
+	}
+
+	if t.Precision.Duration > 0 {
+		if t.Rounding == "round" {
+			tm = tm.Round(t.Precision.Duration)
+		} else {
+			tm = tm.Truncate(t.Precision.Duration)
+		}
+	}
+	return tm, true
+}
+
+func (t *Timestamp) parse(value interface{}) (time.Time, bool) {
+	var err error
+	for _, format := range t.Formats {
+		var tm time.Time
+		tm, err = internal.ParseTimestamp(format, value, t.Timezone)
+		if err == nil {
+			return tm, true
+		}
+	}
+	t.Log.Debugf("Could not parse time %v: %v", value, err)
+	return time.Time{}, false
+}
+
+func init() {
+	processors.Add("timestamp", func() telegraf.Processor {
+		return &Timestamp{
+			Formats:    []string{"unix"},
+			Timezone:   "UTC",
+			OutOfRange: "drop",
+			Rounding:   "truncate",
+		}
+	})
+}
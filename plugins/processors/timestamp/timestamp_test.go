@@ -0,0 +1,180 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTimestamp() *Timestamp {
+	return &Timestamp{
+		Formats:    []string{"unix"},
+		Timezone:   "UTC",
+		OutOfRange: "drop",
+		Rounding:   "truncate",
+		Log:        testutil.Logger{},
+		now:        func() time.Time { return now },
+	}
+}
+
+func TestReceiveTime(t *testing.T) {
+	ts := newTimestamp()
+	ts.UseReceiveTime = true
+	require.NoError(t, ts.Init())
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	out := ts.Apply(m)
+	require.Len(t, out, 1)
+	require.Equal(t, now, out[0].Time())
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		tag      string
+		formats  []string
+		remove   bool
+		input    telegraf.Metric
+		expected telegraf.Metric
+	}{
+		{
+			name:    "unix field",
+			field:   "time",
+			formats: []string{"unix"},
+			input: testutil.MustMetric("trap",
+				map[string]string{},
+				map[string]interface{}{"time": int64(1590000000), "value": 1},
+				now,
+			),
+			expected: testutil.MustMetric("trap",
+				map[string]string{},
+				map[string]interface{}{"time": int64(1590000000), "value": 1},
+				time.Unix(1590000000, 0),
+			),
+		},
+		{
+			name:    "layout tag with fallback",
+			tag:     "time",
+			formats: []string{"unix", "2006-01-02 15:04:05"},
+			remove:  true,
+			input: testutil.MustMetric("syslog",
+				map[string]string{"time": "2020-05-31 10:00:00"},
+				map[string]interface{}{"value": 1},
+				now,
+			),
+			expected: testutil.MustMetric("syslog",
+				map[string]string{},
+				map[string]interface{}{"value": 1},
+				time.Date(2020, 5, 31, 10, 0, 0, 0, time.UTC),
+			),
+		},
+		{
+			name:    "invalid value",
+			field:   "time",
+			formats: []string{"unix_ms"},
+			remove:  true,
+			input: testutil.MustMetric("trap",
+				map[string]string{},
+				map[string]interface{}{"time": "yesterday", "value": 1},
+				now,
+			),
+			expected: testutil.MustMetric("trap",
+				map[string]string{},
+				map[string]interface{}{"time": "yesterday", "value": 1},
+				now,
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTimestamp()
+			ts.SourceField = tt.field
+			ts.SourceTag = tt.tag
+			ts.Formats = tt.formats
+			ts.RemoveSource = tt.remove
+			require.NoError(t, ts.Init())
+
+			out := ts.Apply(tt.input)
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{tt.expected}, out)
+		})
+	}
+}
+
+func TestOffsetAndPrecision(t *testing.T) {
+	ts := newTimestamp()
+	ts.Offset = internal.Duration{Duration: -90 * time.Second}
+	ts.Precision = internal.Duration{Duration: time.Minute}
+	require.NoError(t, ts.Init())
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1},
+		time.Date(2020, 6, 1, 11, 0, 50, 0, time.UTC))
+	out := ts.Apply(m)
+	require.Equal(t, time.Date(2020, 6, 1, 10, 59, 0, 0, time.UTC), out[0].Time())
+
+	ts.Rounding = "round"
+	m = testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1},
+		time.Date(2020, 6, 1, 11, 0, 50, 0, time.UTC))
+	out = ts.Apply(m)
+	require.Equal(t, time.Date(2020, 6, 1, 10, 59, 0, 0, time.UTC), out[0].Time())
+
+	m = testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1},
+		time.Date(2020, 6, 1, 11, 1, 40, 0, time.UTC))
+	out = ts.Apply(m)
+	require.Equal(t, time.Date(2020, 6, 1, 11, 0, 0, 0, time.UTC), out[0].Time())
+}
+
+func TestOutOfRange(t *testing.T) {
+	input := func() []telegraf.Metric {
+		return []telegraf.Metric{
+			testutil.MustMetric("a", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-2*time.Hour)),
+			testutil.MustMetric("b", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-30*time.Minute)),
+			testutil.MustMetric("c", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(10*time.Minute)),
+		}
+	}
+
+	ts := newTimestamp()
+	ts.MaxPast = internal.Duration{Duration: time.Hour}
+	ts.MaxFuture = internal.Duration{Duration: time.Minute}
+	require.NoError(t, ts.Init())
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("b", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-30*time.Minute)),
+	}
+	testutil.RequireMetricsEqual(t, expected, ts.Apply(input()...))
+
+	ts.OutOfRange = "clamp"
+	expected = []telegraf.Metric{
+		testutil.MustMetric("a", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-time.Hour)),
+		testutil.MustMetric("b", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-30*time.Minute)),
+		testutil.MustMetric("c", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(time.Minute)),
+	}
+	testutil.RequireMetricsEqual(t, expected, ts.Apply(input()...))
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(ts *Timestamp)
+	}{
+		{name: "two sources", modify: func(ts *Timestamp) { ts.UseReceiveTime = true; ts.SourceTag = "time" }},
+		{name: "no formats", modify: func(ts *Timestamp) { ts.SourceField = "time"; ts.Formats = nil }},
+		{name: "invalid timezone", modify: func(ts *Timestamp) { ts.Timezone = "Mars/Olympus" }},
+		{name: "invalid out_of_range", modify: func(ts *Timestamp) { ts.OutOfRange = "keep" }},
+		{name: "invalid rounding", modify: func(ts *Timestamp) { ts.Rounding = "ceil" }},
+		{name: "negative precision", modify: func(ts *Timestamp) { ts.Precision.Duration = -time.Second }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTimestamp()
+			tt.modify(ts)
+			require.Error(t, ts.Init())
+		})
+	}
+}
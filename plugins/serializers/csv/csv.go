@@ -0,0 +1,352 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// LayoutWide writes one row per metric with a column for every tag and
+	// field.
+	LayoutWide = "wide"
+	// LayoutLong writes one row per field with the field name and value in
+	// separate columns.
+	LayoutLong = "long"
+)
+
+type columnKind int
+
+const (
+	timestampColumn columnKind = iota
+	measurementColumn
+	tagColumn
+	fieldColumn
+	fieldNameColumn
+	fieldValueColumn
+)
+
+type column struct {
+	kind columnKind
+	key  string
+}
+
+func (c column) header() string {
+	switch c.kind {
+	case timestampColumn:
+		return "timestamp"
+	case measurementColumn:
+		return "measurement"
+	case fieldNameColumn:
+		return "field"
+	case fieldValueColumn:
+		return "value"
+	default:
+		return c.key
+	}
+}
+
+type Config struct {
+	// Columns is the explicit column order, see parseColumn for the syntax.
+	// If empty the columns are timestamp, measurement, sorted tags and
+	// sorted fields.
+	Columns         []string
+	Delimiter       string
+	Header          bool
+	Layout          string
+	TimestampFormat string
+}
+
+type serializer struct {
+	layout          string
+	delimiter       rune
+	header          bool
+	timestampFormat string
+	columns         []column
+
+	// keys are the tags and fields seen of each measurement, so the columns
+	// of a measurement only change when it gets new tags or fields.
+	keys map[string]*keySet
+	// lastHeader is the header written before the last row of Serialize.
+	lastHeader []byte
+}
+
+type keySet struct {
+	tags   map[string]bool
+	fields map[string]bool
+}
+
+func NewSerializer(c *Config) (*serializer, error) {
+	s := &serializer{
+		layout:          c.Layout,
+		delimiter:       ',',
+		header:          c.Header,
+		timestampFormat: c.TimestampFormat,
+		keys:            make(map[string]*keySet),
+	}
+
+	switch s.layout {
+	case "":
+		s.layout = LayoutWide
+	case LayoutWide, LayoutLong:
+	default:
+		return nil, fmt.Errorf("invalid csv_layout %q", c.Layout)
+	}
+
+	if s.timestampFormat == "" {
+		s.timestampFormat = "unix"
+	}
+
+	if c.Delimiter != "" {
+		runes := []rune(c.Delimiter)
+		if len(runes) > 1 {
+			return nil, fmt.Errorf("csv_delimiter must be a single character, got: %s", c.Delimiter)
+		}
+		s.delimiter = runes[0]
+	}
+
+	for _, name := range c.Columns {
+		col, err := parseColumn(name)
+		if err != nil {
+			return nil, err
+		}
+		if s.layout == LayoutWide && (col.kind == fieldNameColumn || col.kind == fieldValueColumn) {
+			return nil, fmt.Errorf("csv_columns %q is only valid with the long layout", name)
+		}
+		if s.layout == LayoutLong && col.kind == fieldColumn {
+			return nil, fmt.Errorf("csv_columns %q is only valid with the wide layout", name)
+		}
+		s.columns = append(s.columns, col)
+	}
+
+	return s, nil
+}
+
+// parseColumn converts a column name from the configuration, one of
+// "timestamp", "measurement", "field", "value", "tag.<key>" or
+// "field.<key>".
+func parseColumn(name string) (column, error) {
+	switch {
+	case name == "timestamp":
+		return column{kind: timestampColumn}, nil
+	case name == "measurement":
+		return column{kind: measurementColumn}, nil
+	case name == "field":
+		return column{kind: fieldNameColumn}, nil
+	case name == "value":
+		return column{kind: fieldValueColumn}, nil
+	case strings.HasPrefix(name, "tag."):
+		return column{kind: tagColumn, key: strings.TrimPrefix(name, "tag.")}, nil
+	case strings.HasPrefix(name, "field."):
+		return column{kind: fieldColumn, key: strings.TrimPrefix(name, "field.")}, nil
+	default:
+		return column{}, fmt.Errorf("invalid csv_columns entry %q", name)
+	}
+}
+
+// Serialize writes the metric as one or more rows.  The header is written
+// before the first row and whenever the columns change.
+func (s *serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	header, rows, err := s.SerializeRows([]telegraf.Metric{metric})
+	if err != nil {
+		return nil, err
+	}
+	if header == nil || bytes.Equal(header, s.lastHeader) {
+		return rows, nil
+	}
+	s.lastHeader = header
+	return append(header, rows...), nil
+}
+
+// SerializeBatch writes the metrics as rows, preceded by the header if
+// enabled.
+func (s *serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	header, rows, err := s.SerializeRows(metrics)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, rows...), nil
+}
+
+// SerializeRows writes the metrics as rows and returns the header for them
+// separately, nil if the header is disabled.  Unless configured, the columns
+// are the tags and fields seen so far of the measurements of the metrics.
+func (s *serializer) SerializeRows(metrics []telegraf.Metric) ([]byte, []byte, error) {
+	columns := s.columnsFor(metrics)
+
+	var header []byte
+	if s.header {
+		var buf bytes.Buffer
+		w := s.newWriter(&buf)
+		if err := s.writeHeader(w, columns); err != nil {
+			return nil, nil, err
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, nil, err
+		}
+		header = buf.Bytes()
+	}
+
+	var buf bytes.Buffer
+	w := s.newWriter(&buf)
+	for _, metric := range metrics {
+		if err := s.writeMetric(w, columns, metric); err != nil {
+			return nil, nil, err
+		}
+	}
+	w.Flush()
+	return header, buf.Bytes(), w.Error()
+}
+
+func (s *serializer) newWriter(buf *bytes.Buffer) *csv.Writer {
+	w := csv.NewWriter(buf)
+	w.Comma = s.delimiter
+	return w
+}
+
+// columnsFor returns the configured columns or, if none are configured, the
+// default columns covering all tags and fields seen of the measurements of
+// the metrics.
+func (s *serializer) columnsFor(metrics []telegraf.Metric) []column {
+	if len(s.columns) > 0 {
+		return s.columns
+	}
+
+	names := make(map[string]bool)
+	for _, metric := range metrics {
+		names[metric.Name()] = true
+		keys, ok := s.keys[metric.Name()]
+		if !ok {
+			keys = &keySet{tags: make(map[string]bool), fields: make(map[string]bool)}
+			s.keys[metric.Name()] = keys
+		}
+		for _, tag := range metric.TagList() {
+			keys.tags[tag.Key] = true
+		}
+		for _, field := range metric.FieldList() {
+			keys.fields[field.Key] = true
+		}
+	}
+
+	tagKeys := make(map[string]bool)
+	fieldKeys := make(map[string]bool)
+	for name := range names {
+		keys := s.keys[name]
+		for key := range keys.tags {
+			tagKeys[key] = true
+		}
+		for key := range keys.fields {
+			fieldKeys[key] = true
+		}
+	}
+
+	columns := []column{{kind: timestampColumn}, {kind: measurementColumn}}
+	for _, key := range sortedKeys(tagKeys) {
+		columns = append(columns, column{kind: tagColumn, key: key})
+	}
+	if s.layout == LayoutLong {
+		return append(columns, column{kind: fieldNameColumn}, column{kind: fieldValueColumn})
+	}
+	for _, key := range sortedKeys(fieldKeys) {
+		columns = append(columns, column{kind: fieldColumn, key: key})
+	}
+	return columns
+}
+
+func (s *serializer) writeHeader(w *csv.Writer, columns []column) error {
+	record := make([]string, 0, len(columns))
+	for _, col := range columns {
+		record = append(record, col.header())
+	}
+	return w.Write(record)
+}
+
+func (s *serializer) writeMetric(w *csv.Writer, columns []column, metric telegraf.Metric) error {
+	if s.layout == LayoutWide {
+		return w.Write(s.record(columns, metric, nil))
+	}
+
+	fields := metric.FieldList()
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	for _, field := range fields {
+		if err := w.Write(s.record(columns, metric, field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record creates the row for the metric, field is only set for the long
+// layout.
+func (s *serializer) record(columns []column, metric telegraf.Metric, field *telegraf.Field) []string {
+	record := make([]string, 0, len(columns))
+	for _, col := range columns {
+		var value string
+		switch col.kind {
+		case timestampColumn:
+			value = s.formatTimestamp(metric.Time())
+		case measurementColumn:
+			value = metric.Name()
+		case tagColumn:
+			value, _ = metric.GetTag(col.key)
+		case fieldColumn:
+			if v, ok := metric.GetField(col.key); ok {
+				value = formatValue(v)
+			}
+		case fieldNameColumn:
+			value = field.Key
+		case fieldValueColumn:
+			value = formatValue(field.Value)
+		}
+		record = append(record, value)
+	}
+	return record
+}
+
+func (s *serializer) formatTimestamp(tm time.Time) string {
+	switch s.timestampFormat {
+	case "unix":
+		return strconv.FormatInt(tm.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(tm.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(tm.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(tm.UnixNano(), 10)
+	default:
+		return tm.UTC().Format(s.timestampFormat)
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,208 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "server01",
+				"cpu":  "cpu0",
+			},
+			map[string]interface{}{
+				"usage_idle": 91.5,
+				"usage_user": 2.5,
+			},
+			time.Unix(1592846400, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "server02",
+				"cpu":  "cpu0",
+			},
+			map[string]interface{}{
+				"usage_idle": 42.0,
+				"count":      uint64(7),
+			},
+			time.Unix(1592846410, 0),
+		),
+	}
+}
+
+func TestSerializeWide(t *testing.T) {
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[0])
+	require.NoError(t, err)
+	require.Equal(t, "1592846400,cpu,cpu0,server01,91.5,2.5\n", string(buf))
+}
+
+func TestSerializeHeaderOnColumnChange(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true})
+	require.NoError(t, err)
+
+	var out string
+	for _, m := range append(testMetrics(), testMetrics()[0]) {
+		buf, err := s.Serialize(m)
+		require.NoError(t, err)
+		out += string(buf)
+	}
+
+	// The columns of a measurement only grow, so the header is written
+	// again when the second metric adds the count field.
+	expected := "timestamp,measurement,cpu,host,usage_idle,usage_user\n" +
+		"1592846400,cpu,cpu0,server01,91.5,2.5\n" +
+		"timestamp,measurement,cpu,host,count,usage_idle,usage_user\n" +
+		"1592846410,cpu,cpu0,server02,7,42,\n" +
+		"1592846400,cpu,cpu0,server01,,91.5,2.5\n"
+	require.Equal(t, expected, out)
+}
+
+func TestSerializeMeasurements(t *testing.T) {
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testutil.MustMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1},
+		time.Unix(0, 0),
+	))
+	require.NoError(t, err)
+	require.Equal(t, "0,cpu,a,1\n", string(buf))
+
+	// Each measurement has its own columns, no field is left out.
+	buf, err = s.Serialize(testutil.MustMetric("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": 2},
+		time.Unix(0, 0),
+	))
+	require.NoError(t, err)
+	require.Equal(t, "0,mem,a,2\n", string(buf))
+}
+
+func TestSerializeBatchWide(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true, Delimiter: ";"})
+	require.NoError(t, err)
+
+	expected := "timestamp;measurement;cpu;host;count;usage_idle;usage_user\n" +
+		"1592846400;cpu;cpu0;server01;;91.5;2.5\n" +
+		"1592846410;cpu;cpu0;server02;7;42;\n"
+
+	// Every batch starts with the header.
+	for i := 0; i < 2; i++ {
+		buf, err := s.SerializeBatch(testMetrics())
+		require.NoError(t, err)
+		require.Equal(t, expected, string(buf))
+	}
+}
+
+func TestSerializeRows(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true, Layout: LayoutLong})
+	require.NoError(t, err)
+
+	header, rows, err := s.SerializeRows(testMetrics()[:1])
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,cpu,host,field,value\n", string(header))
+	require.Equal(t, "1592846400,cpu,cpu0,server01,usage_idle,91.5\n"+
+		"1592846400,cpu,cpu0,server01,usage_user,2.5\n", string(rows))
+
+	s, err = NewSerializer(&Config{})
+	require.NoError(t, err)
+	header, _, err = s.SerializeRows(testMetrics()[:1])
+	require.NoError(t, err)
+	require.Nil(t, header)
+}
+
+func TestSerializeBatchLong(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true, Layout: LayoutLong})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	expected := "timestamp,measurement,cpu,host,field,value\n" +
+		"1592846400,cpu,cpu0,server01,usage_idle,91.5\n" +
+		"1592846400,cpu,cpu0,server01,usage_user,2.5\n" +
+		"1592846410,cpu,cpu0,server02,count,7\n" +
+		"1592846410,cpu,cpu0,server02,usage_idle,42\n"
+	require.Equal(t, expected, string(buf))
+}
+
+func TestSerializeColumns(t *testing.T) {
+	s, err := NewSerializer(&Config{
+		Header:          true,
+		Columns:         []string{"field.usage_idle", "tag.host", "timestamp"},
+		TimestampFormat: time.RFC3339,
+	})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	expected := "usage_idle,host,timestamp\n" +
+		"91.5,server01,2020-06-22T17:20:00Z\n" +
+		"42,server02,2020-06-22T17:20:10Z\n"
+	require.Equal(t, expected, string(buf))
+}
+
+func TestSerializeQuoting(t *testing.T) {
+	s, err := NewSerializer(&Config{TimestampFormat: "unix_ms"})
+	require.NoError(t, err)
+
+	m := testutil.MustMetric(
+		"log",
+		map[string]string{},
+		map[string]interface{}{
+			"message": `hello, "world"`,
+			"ok":      true,
+		},
+		time.Unix(0, 1500000000),
+	)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "1500,log,\"hello, \"\"world\"\"\",true\n", string(buf))
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "invalid layout",
+			config: &Config{Layout: "tall"},
+		},
+		{
+			name:   "long delimiter",
+			config: &Config{Delimiter: "::"},
+		},
+		{
+			name:   "invalid column",
+			config: &Config{Columns: []string{"host"}},
+		},
+		{
+			name:   "value column in wide layout",
+			config: &Config{Columns: []string{"value"}},
+		},
+		{
+			name:   "field column in long layout",
+			config: &Config{Layout: LayoutLong, Columns: []string{"field.usage_idle"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSerializer(tt.config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	// timestampExtension is the MessagePack extension type reserved for
+	// timestamps, -1 as a signed byte.
+	timestampExtension = 0xff
+
+	// uint64Marker is the MessagePack type marker for a 64 bit unsigned
+	// integer.
+	uint64Marker = 0xcf
+)
+
+// Serializer encodes each metric as a MessagePack map with the keys "name",
+// "time", "tags" and "fields".  The time uses the timestamp extension type
+// with nanosecond precision.
+type Serializer struct {
+}
+
+func NewSerializer() *Serializer {
+	return &Serializer{}
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return appendMetric(nil, metric), nil
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, metric := range metrics {
+		buf = appendMetric(buf, metric)
+	}
+	return buf, nil
+}
+
+func appendMetric(b []byte, metric telegraf.Metric) []byte {
+	b = msgp.AppendMapHeader(b, 4)
+
+	b = msgp.AppendString(b, "name")
+	b = msgp.AppendString(b, metric.Name())
+
+	b = msgp.AppendString(b, "time")
+	b = appendTime(b, metric.Time())
+
+	b = msgp.AppendString(b, "tags")
+	tags := metric.TagList()
+	b = msgp.AppendMapHeader(b, uint32(len(tags)))
+	for _, tag := range tags {
+		b = msgp.AppendString(b, tag.Key)
+		b = msgp.AppendString(b, tag.Value)
+	}
+
+	b = msgp.AppendString(b, "fields")
+	fields := metric.FieldList()
+	b = msgp.AppendMapHeader(b, uint32(len(fields)))
+	for _, field := range fields {
+		b = msgp.AppendString(b, field.Key)
+		b = appendValue(b, field.Value)
+	}
+	return b
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case float64:
+		return msgp.AppendFloat64(b, v)
+	case int64:
+		return msgp.AppendInt64(b, v)
+	case uint64:
+		// Always use the full width unsigned encoding, small values would
+		// otherwise be written as positive fixint and decoded as int64.
+		b = append(b, uint64Marker)
+		return appendUint64(b, v)
+	case bool:
+		return msgp.AppendBool(b, v)
+	case string:
+		return msgp.AppendString(b, v)
+	default:
+		return msgp.AppendNil(b)
+	}
+}
+
+// appendTime writes the time using the 96 bit format of the timestamp
+// extension: a 32 bit nanosecond part followed by 64 bit seconds.
+func appendTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xc7, 12, timestampExtension)
+	b = appendUint32(b, uint32(t.Nanosecond()))
+	return appendUint64(b, uint64(t.Unix()))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,77 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func testMetric() telegraf.Metric {
+	return testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host": "server01",
+		},
+		map[string]interface{}{
+			"float":  42.5,
+			"int":    int64(-3),
+			"uint":   uint64(7),
+			"bool":   true,
+			"string": "ok",
+		},
+		time.Unix(1592846400, 123456789),
+	)
+}
+
+func TestSerialize(t *testing.T) {
+	s := NewSerializer()
+	buf, err := s.Serialize(testMetric())
+	require.NoError(t, err)
+
+	v, rest, err := msgp.ReadIntfBytes(buf)
+	require.NoError(t, err)
+	require.Len(t, rest, 0)
+
+	obj, ok := v.(map[string]interface{})
+	require.True(t, ok)
+	require.Equal(t, "cpu", obj["name"])
+	require.Equal(t, map[string]interface{}{"host": "server01"}, obj["tags"])
+	require.Equal(t, map[string]interface{}{
+		"float":  42.5,
+		"int":    int64(-3),
+		"uint":   uint64(7),
+		"bool":   true,
+		"string": "ok",
+	}, obj["fields"])
+
+	ext, ok := obj["time"].(*msgp.RawExtension)
+	require.True(t, ok)
+	require.Equal(t, int8(-1), ext.Type)
+	require.Equal(t, []byte{
+		0x07, 0x5b, 0xcd, 0x15,
+		0x00, 0x00, 0x00, 0x00, 0x5e, 0xf0, 0xe8, 0x40,
+	}, ext.Data)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"value": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+
+	s := NewSerializer()
+	single, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{m, m})
+	require.NoError(t, err)
+	require.Equal(t, append(append([]byte{}, single...), single...), buf)
+}
@@ -6,12 +6,15 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/template"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
 
@@ -42,6 +45,18 @@ type Serializer interface {
 	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
 }
 
+// HeaderSerializer is a Serializer for formats with a header naming the
+// columns, such as csv.  Outputs writing to files use it to write the header
+// at the start of each file and when the columns change, instead of with
+// every batch.
+type HeaderSerializer interface {
+	Serializer
+
+	// SerializeRows serializes the metrics like SerializeBatch, but returns
+	// the header separately.  The header is nil if it is disabled.
+	SerializeRows(metrics []telegraf.Metric) ([]byte, []byte, error)
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
@@ -67,10 +82,13 @@ type Config struct {
 	// Prefix to add to all measurements, only supports Graphite
 	Prefix string `toml:"prefix"`
 
-	// Template for converting telegraf metrics into Graphite
-	// only supports Graphite
+	// Template for converting telegraf metrics into Graphite, or the Go
+	// template executed for each metric with the template format
 	Template string `toml:"template"`
 
+	// Go template executed for each batch; template format only
+	BatchTemplate string `toml:"batch_template"`
+
 	// Templates same Template, but multiple
 	Templates []string `toml:"templates"`
 
@@ -100,6 +118,25 @@ type Config struct {
 	// Output string fields as metric labels; when false string fields are
 	// discarded.
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
+
+	// Column order for the csv format, "timestamp", "measurement",
+	// "tag.<key>" and "field.<key>"; or "field" and "value" for the long
+	// layout.
+	CSVColumns []string `toml:"csv_columns"`
+
+	// Separator between csv values, defaults to ",".
+	CSVDelimiter string `toml:"csv_delimiter"`
+
+	// Write a header row before the csv data.
+	CSVHeader bool `toml:"csv_header"`
+
+	// Row layout for csv, "wide" for one row per metric or "long" for one
+	// row per field.
+	CSVLayout string `toml:"csv_layout"`
+
+	// Format of the csv timestamp column, "unix", "unix_ms", "unix_us",
+	// "unix_ns" or a Go time layout.
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -123,6 +160,12 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
 	case "prometheus":
 		serializer, err = NewPrometheusSerializer(config)
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
+	case "template":
+		serializer, err = NewTemplateSerializer(config.Template, config.BatchTemplate)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -152,6 +195,24 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	})
 }
 
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	return csv.NewSerializer(&csv.Config{
+		Columns:         config.CSVColumns,
+		Delimiter:       config.CSVDelimiter,
+		Header:          config.CSVHeader,
+		Layout:          config.CSVLayout,
+		TimestampFormat: config.CSVTimestampFormat,
+	})
+}
+
+func NewMsgpackSerializer() (Serializer, error) {
+	return msgpack.NewSerializer(), nil
+}
+
+func NewTemplateSerializer(metricTemplate, batchTemplate string) (Serializer, error) {
+	return template.NewSerializer(metricTemplate, batchTemplate)
+}
+
 func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
 }
@@ -0,0 +1,113 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// funcs are the helper functions available in addition to the builtin
+// template functions.
+var funcs = template.FuncMap{
+	"json":       toJSON,
+	"formatTime": formatTime,
+}
+
+// Serializer renders metrics using Go templates.  The metric template is
+// executed with a *Metric and the batch template with a []*Metric.
+type Serializer struct {
+	metricTemplate *template.Template
+	batchTemplate  *template.Template
+}
+
+// NewSerializer parses the templates, at least one of them must be set.
+func NewSerializer(metricTemplate, batchTemplate string) (*Serializer, error) {
+	if metricTemplate == "" && batchTemplate == "" {
+		return nil, errors.New("template or batch_template must be set")
+	}
+
+	s := &Serializer{}
+	var err error
+	if metricTemplate != "" {
+		s.metricTemplate, err = template.New("template").Funcs(funcs).Parse(metricTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %v", err)
+		}
+	}
+	if batchTemplate != "" {
+		s.batchTemplate, err = template.New("batch_template").Funcs(funcs).Parse(batchTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing batch_template: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Serialize renders the metric template, if only a batch template is set it
+// is rendered with a batch containing just this metric.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.metricTemplate == nil {
+		return s.SerializeBatch([]telegraf.Metric{metric})
+	}
+
+	var buf bytes.Buffer
+	if err := s.metricTemplate.Execute(&buf, &Metric{metric: metric}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SerializeBatch renders the batch template, if only a metric template is
+// set the output of each metric is concatenated.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	if s.batchTemplate == nil {
+		for _, metric := range metrics {
+			if err := s.metricTemplate.Execute(&buf, &Metric{metric: metric}); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+
+	batch := make([]*Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		batch = append(batch, &Metric{metric: metric})
+	}
+	if err := s.batchTemplate.Execute(&buf, batch); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toJSON encodes the value as JSON, strings are quoted and escaped.
+func toJSON(v interface{}) (string, error) {
+	octets, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(octets), nil
+}
+
+// formatTime formats the time as "unix", "unix_ms", "unix_us", "unix_ns" or
+// using a Go "reference time" layout in UTC.
+func formatTime(t time.Time, format string) string {
+	switch format {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	default:
+		return t.UTC().Format(format)
+	}
+}
@@ -0,0 +1,49 @@
+package template
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Metric is the value passed to the templates.
+type Metric struct {
+	metric telegraf.Metric
+}
+
+func (m *Metric) Name() string {
+	return m.metric.Name()
+}
+
+func (m *Metric) Tag(key string) string {
+	tagString, _ := m.metric.GetTag(key)
+	return tagString
+}
+
+func (m *Metric) Tags() map[string]string {
+	return m.metric.Tags()
+}
+
+func (m *Metric) Field(key string) interface{} {
+	field, _ := m.metric.GetField(key)
+	return field
+}
+
+func (m *Metric) Fields() map[string]interface{} {
+	return m.metric.Fields()
+}
+
+func (m *Metric) Time() time.Time {
+	return m.metric.Time()
+}
+
+// MarshalJSON allows the whole metric to be written with the json helper.
+func (m *Metric) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"name":      m.metric.Name(),
+		"tags":      m.metric.Tags(),
+		"fields":    m.metric.Fields(),
+		"timestamp": m.metric.Time().Unix(),
+	})
+}
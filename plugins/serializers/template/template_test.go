@@ -0,0 +1,96 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "server01",
+			},
+			map[string]interface{}{
+				"usage_idle": 91.5,
+				"usage_user": 2.5,
+			},
+			time.Unix(1592846400, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": `server "02"`,
+			},
+			map[string]interface{}{
+				"usage_idle": 42.0,
+			},
+			time.Unix(1592846410, 0),
+		),
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(`{{ .Name }},{{ .Tag "host" }}{{ range $k, $v := .Fields }} {{ $k }}={{ $v }}{{ end }} {{ formatTime .Time "unix_ms" }}`+"\n", "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[0])
+	require.NoError(t, err)
+	require.Equal(t, "cpu,server01 usage_idle=91.5 usage_user=2.5 1592846400000\n", string(buf))
+}
+
+func TestSerializeBatchConcatenates(t *testing.T) {
+	s, err := NewSerializer(`{{ json (.Tag "host") }}`+"\n", "")
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, "\"server01\"\n\"server \\\"02\\\"\"\n", string(buf))
+}
+
+func TestSerializeBatchTemplate(t *testing.T) {
+	s, err := NewSerializer("", `[{{ range $i, $m := . }}{{ if $i }},{{ end }}{{ formatTime $m.Time "2006-01-02T15:04:05Z07:00" }}{{ end }}]`)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, "[2020-06-22T17:20:00Z,2020-06-22T17:20:10Z]", string(buf))
+
+	// Without a metric template each metric is a batch of one.
+	buf, err = s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, "[2020-06-22T17:20:10Z]", string(buf))
+}
+
+func TestSerializeJSONMetric(t *testing.T) {
+	s, err := NewSerializer(`{{ json . }}`, "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"cpu","tags":{"host":"server \"02\""},"fields":{"usage_idle":42},"timestamp":1592846410}`, string(buf))
+}
+
+func TestSerializeExecuteError(t *testing.T) {
+	s, err := NewSerializer(`{{ .Missing }}`, "")
+	require.NoError(t, err)
+
+	_, err = s.Serialize(testMetrics()[0])
+	require.Error(t, err)
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	_, err := NewSerializer("", "")
+	require.Error(t, err)
+
+	_, err = NewSerializer("{{ .Name", "")
+	require.Error(t, err)
+
+	_, err = NewSerializer("", "{{ end }}")
+	require.Error(t, err)
+}